@@ -59,7 +59,7 @@ require (
 )
 
 require (
-	github.com/emirpasic/gods v1.12.0 // indirect
+	github.com/emirpasic/gods v1.12.0
 	github.com/gin-contrib/size v1.0.2
 	github.com/golang/mock v1.6.0 // indirect
 	github.com/google/uuid v1.3.0 // indirect
@@ -87,6 +87,8 @@ require (
 )
 
 require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/lib/pq v1.10.9
 	github.com/shirou/gopsutil v3.21.11+incompatible
 )
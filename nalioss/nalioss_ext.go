@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"sort"
 	"sync"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
@@ -124,7 +126,11 @@ func (svc *NAliOssClient) MultipartUpload(objKey, localFile string, chunkSize in
 		return err
 	}
 	uploadId := *initResult.UploadId
-	file, _ := os.Open(localFile)
+	file, err := os.Open(localFile)
+	if err != nil {
+		svc.abortMultipartUpload(objKey, uploadId)
+		return err
+	}
 	defer file.Close()
 
 	// 初始化等待组和互斥锁
@@ -132,20 +138,39 @@ func (svc *NAliOssClient) MultipartUpload(objKey, localFile string, chunkSize in
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	parts := make([]oss.UploadPart, 0)
+	// 第一个失败的分片错误,出现后不再提交新的分片
+	var partErr error
+	setPartErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if partErr == nil {
+			partErr = err
+		}
+	}
 	for {
 		offset := partNumber * chunkSize
 		currentChunkSize := min(chunkSize, fileInfo.Size()-offset)
 		if currentChunkSize <= 0 {
 			break
 		}
+		mu.Lock()
+		failed := partErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
 		chunkData := make([]byte, currentChunkSize)
-		file.Read(chunkData)
+		if _, err := io.ReadFull(file, chunkData); err != nil {
+			setPartErr(nerror.NewRunTimeErrorWithError("读取分片失败", err))
+			break
+		}
 
 		wg.Add(1)
 		curPartNumber := partNumber + 1
 		slog.Debug(fmt.Sprintf("分片序号:%d,当前分片大小:%s", curPartNumber, ntools.FileSize2Str(currentChunkSize)))
 
-		svc.MultipartUploadWorkPool.Submit(func() {
+		err := svc.MultipartUploadWorkPool.Submit(func() {
+			defer wg.Done()
 			// 创建分片上传请求
 			partRequest := &oss.UploadPartRequest{
 				Bucket:     oss.Ptr(svc.Cnf.BucketName), // 目标存储空间名称
@@ -158,18 +183,30 @@ func (svc *NAliOssClient) MultipartUpload(objKey, localFile string, chunkSize in
 			partResult, err := retryUploadPart(svc.OssClient, partRequest, 1, 3)
 			if err != nil {
 				slog.Error(fmt.Sprintf("分片上传失败 %d: %v", curPartNumber, err))
+				setPartErr(err)
+				return
 			}
 			// 记录分片上传结果
 			mu.Lock()
 			parts = append(parts, oss.UploadPart{PartNumber: partRequest.PartNumber, ETag: partResult.ETag})
 			mu.Unlock()
-			wg.Done()
 			slog.Debug(fmt.Sprintf("分片序号:%d,已上传完成", curPartNumber))
 		})
+		if err != nil {
+			wg.Done()
+			setPartErr(nerror.NewRunTimeErrorWithError("提交分片上传任务失败", err))
+			break
+		}
 		// 增加
 		partNumber++
 	}
 	wg.Wait()
+	if partErr != nil {
+		svc.abortMultipartUpload(objKey, uploadId)
+		return partErr
+	}
+	// 分片需按序号升序提交
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
 	// 完成分片上传请求
 	request := &oss.CompleteMultipartUploadRequest{
 		Bucket:                  oss.Ptr(svc.Cnf.BucketName),
@@ -180,18 +217,36 @@ func (svc *NAliOssClient) MultipartUpload(objKey, localFile string, chunkSize in
 	_, err = svc.OssClient.CompleteMultipartUpload(context.TODO(), request)
 	if err != nil {
 		slog.Error("完成分片上传请求，执行失败:" + nerror.GenErrDetail(err))
+		svc.abortMultipartUpload(objKey, uploadId)
 		return err
 	}
 	slog.Info(fmt.Sprintf("本地文件:%s,已上传到:%s", localFile, objKey))
 	return err
 }
 
+// 取消分片上传,释放已上传的分片
+func (svc *NAliOssClient) abortMultipartUpload(objKey, uploadId string) {
+	request := &oss.AbortMultipartUploadRequest{
+		Bucket:   oss.Ptr(svc.Cnf.BucketName),
+		Key:      oss.Ptr(objKey),
+		UploadId: oss.Ptr(uploadId),
+	}
+	if _, err := svc.OssClient.AbortMultipartUpload(context.TODO(), request); err != nil {
+		slog.Error("取消分片上传失败:" + nerror.GenErrDetail(err))
+	}
+}
+
+// 重试上传分片,超过retryMaxTimes次仍失败时返回最后一次的错误
 func retryUploadPart(client *oss.Client, requst *oss.UploadPartRequest, curTimes, retryMaxTimes int) (partResult *oss.UploadPartResult, err error) {
 	slog.Debug(fmt.Sprintf("分片序号:%v,第%v/%v次上传", requst.PartNumber, curTimes, retryMaxTimes))
 	partResult, err = client.UploadPart(context.TODO(), requst)
 	curTimes = curTimes + 1
 	if err == nil || curTimes > retryMaxTimes {
-		return partResult, nil
+		return partResult, err
+	}
+	// 重试前重置分片内容的读取位置
+	if seeker, ok := requst.Body.(io.Seeker); ok {
+		seeker.Seek(0, io.SeekStart)
 	}
 	return retryUploadPart(client, requst, curTimes, retryMaxTimes)
 }
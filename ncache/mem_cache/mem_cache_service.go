@@ -1,19 +1,72 @@
 package mencache
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/emirpasic/gods/trees/redblacktree"
 	"github.com/niexqc/nlibs/nerror"
 	"github.com/patrickmn/go-cache"
 )
 
+// 内存队列的容量
+const memQueueSize = 10000
+
+// 索引中的Key达到该数量后开始清理已过期或删除的Key
+const memKeyIdxMinSweep = 1024
+
 // 内存缓存
 // MemCacheService ...
+// 通过MemCacheService方法写入的Key会记录在有序索引中,ClearByKeyPrefix只遍历匹配前缀的Key
+// Key必须通过MemCacheService的方法写入,直接操作Cache写入的Key不在索引中,不会被ClearByKeyPrefix清理
+// 索引不依赖Cache.OnEvicted,调用方可自行设置淘汰回调;已过期或删除的Key在索引增长到一定数量时批量清理
 type MemCacheService struct {
 	Cache *cache.Cache
 	nmu   sync.RWMutex
+	//Key的有序索引
+	keyIdx  *redblacktree.Tree
+	idxMu   sync.Mutex
+	idxOnce sync.Once
+	//索引达到该数量时清理
+	nextSweep int
+	//命名队列
+	queues map[string]chan string
+	qmu    sync.Mutex
+}
+
+func (service *MemCacheService) initKeyIdx() {
+	service.idxOnce.Do(func() {
+		service.keyIdx = redblacktree.NewWithStringComparator()
+		service.nextSweep = memKeyIdxMinSweep
+	})
+}
+
+func (service *MemCacheService) addKeyIdx(key string) {
+	service.initKeyIdx()
+	service.idxMu.Lock()
+	defer service.idxMu.Unlock()
+	service.keyIdx.Put(key, struct{}{})
+	if service.keyIdx.Size() >= service.nextSweep {
+		service.sweepKeyIdx()
+	}
+}
+
+// 移除索引中已不存在的Key,下次在存活数量的2倍时再清理,均摊后每次写入为O(1)
+func (service *MemCacheService) sweepKeyIdx() {
+	removes := []any{}
+	for it := service.keyIdx.Iterator(); it.Next(); {
+		if _, found := service.Cache.Get(it.Key().(string)); !found {
+			removes = append(removes, it.Key())
+		}
+	}
+	for _, key := range removes {
+		service.keyIdx.Remove(key)
+	}
+	service.nextSweep = max(memKeyIdxMinSweep, service.keyIdx.Size()*2)
 }
 
 // Int64Incr implements INcache.
@@ -23,6 +76,7 @@ func (service *MemCacheService) Int64Incr(key string, expireMillisecond int64) (
 	val, fund := service.Cache.Get(key)
 	if !fund {
 		err = service.Cache.Add(key, int64(1), time.Duration(expireMillisecond)*time.Second)
+		service.addKeyIdx(key)
 		return int64(1), err
 	} else {
 		if v, cok := val.(int64); cok {
@@ -37,6 +91,7 @@ func (service *MemCacheService) Int64Incr(key string, expireMillisecond int64) (
 // PutStr ...
 func (service *MemCacheService) PutStr(key string, val string) error {
 	service.Cache.SetDefault(key, val)
+	service.addKeyIdx(key)
 	return nil
 }
 
@@ -47,12 +102,28 @@ func (service *MemCacheService) PutExStr(key string, val string, sencond int) er
 	defer service.nmu.Unlock()
 	service.ClearKey(key)
 	err := service.Cache.Add(key, val, time.Duration(sencond)*time.Second)
+	service.addKeyIdx(key)
 	return err
 }
 
 // 仅在【key​不存在】​​时成功（​​原子性操作​​）
 func (service *MemCacheService) PutNxExStr(key string, val string, sencond int) error {
-	panic(nerror.NewRunTimeError("未实现 PutNxExStr"))
+	return service.putNx(key, val, time.Duration(sencond)*time.Second)
+}
+
+// 仅在key不存在时写入,key已存在返回false
+func (service *MemCacheService) TryPutNxExStr(key string, val string, sencond int) (bool, error) {
+	return service.putNx(key, val, time.Duration(sencond)*time.Second) == nil, nil
+}
+
+func (service *MemCacheService) putNx(key string, val string, expiry time.Duration) error {
+	service.nmu.Lock()
+	defer service.nmu.Unlock()
+	if err := service.Cache.Add(key, val, expiry); err != nil {
+		return nerror.NewRunTimeErrorWithError("key已存在", err)
+	}
+	service.addKeyIdx(key)
+	return nil
 }
 
 // GetStr ...
@@ -92,13 +163,26 @@ func (service *MemCacheService) KeySetExpire(key string, sencond int) error {
 
 // ClearByKeyPrefix 清理指定前缀的KEY
 func (service *MemCacheService) ClearByKeyPrefix(keyPrefix string) (int, error) {
-	maps := service.Cache.Items()
+	service.initKeyIdx()
+	service.idxMu.Lock()
+	keys := []string{}
+	node, found := service.keyIdx.Ceiling(keyPrefix)
+	for found {
+		key := node.Key.(string)
+		if !strings.HasPrefix(key, keyPrefix) {
+			break
+		}
+		keys = append(keys, key)
+		node, found = service.keyIdx.Ceiling(key + "\x00")
+	}
+	service.idxMu.Unlock()
+
 	count := 0
-	for k, _ := range maps {
-		if strings.HasPrefix(k, keyPrefix) {
+	for _, key := range keys {
+		if _, ok := service.Cache.Get(key); ok {
 			count++
-			service.ClearKey(k)
 		}
+		service.ClearKey(key)
 	}
 	return count, nil
 }
@@ -109,16 +193,72 @@ func (service *MemCacheService) ClearKey(key string) error {
 	return nil
 }
 
+// expiry 过期时间-秒
+// tries 重试次数
+// delay 重试间隔时间-毫秒
 func (service *MemCacheService) LockRun(key, value string, expiry int, tries, delay int, lockFun func() any) (result any, err error) {
-	panic(nerror.NewRunTimeError("未实现 LockRun"))
+	mutex := service.NewMutex(key, value, expiry, tries, delay)
+	if mutex.MemLock() {
+		defer mutex.MemReleseLock()
+		return lockFun(), nil
+	}
+	return nil, nerror.NewRunTimeError(fmt.Sprintf("[%v]-[%v]未获取到锁", key, value))
+}
+
+func (service *MemCacheService) NewMutex(k, v string, expiry, tries, delay int) *MemMutex {
+	return MemNewMutex(k, v, service, time.Duration(expiry)*time.Second, tries, time.Duration(delay)*time.Millisecond)
 }
 
-// 队列消息写入
+func (service *MemCacheService) queue(queueKey string) chan string {
+	service.qmu.Lock()
+	defer service.qmu.Unlock()
+	if service.queues == nil {
+		service.queues = map[string]chan string{}
+	}
+	queue, ok := service.queues[queueKey]
+	if !ok {
+		queue = make(chan string, memQueueSize)
+		service.queues[queueKey] = queue
+	}
+	return queue
+}
+
+// 队列消息写入,队列已满时返回错误
 func (service *MemCacheService) Producer(queueKey string, message string) error {
-	panic(nerror.NewRunTimeError("未实现 Producer"))
+	queue := service.queue(queueKey)
+	select {
+	case queue <- message:
+		slog.Debug(fmt.Sprintf("队列[%s]中目前有[%v]条数据,当前写入[%v]", queueKey, len(queue), message))
+		return nil
+	default:
+		return nerror.NewRunTimeErrorFmt("队列[%s]已满,最多%d条", queueKey, memQueueSize)
+	}
 }
 
-// 队列消息读取
-func (service *MemCacheService) Consumer(queueKey string, message string) {
-	panic(nerror.NewRunTimeError("未实现 Consumer"))
+// 队列消息读取,阻塞读取并写入msgch,与RedisService.Consumer一致不会返回
+func (service *MemCacheService) Consumer(queueKey string, msgch chan string) {
+	service.ConsumerCtx(context.Background(), queueKey, msgch)
+}
+
+// 队列消息读取,阻塞读取并写入msgch,ctx取消后返回
+// 已取出但未能写入msgch的消息会放回队列末尾
+func (service *MemCacheService) ConsumerCtx(ctx context.Context, queueKey string, msgch chan string) {
+	queue := service.queue(queueKey)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-queue:
+			select {
+			case msgch <- message:
+			case <-ctx.Done():
+				select {
+				case queue <- message:
+				default:
+					slog.Warn(fmt.Sprintf("队列[%s]已满,消息[%s]未能放回", queueKey, message))
+				}
+				return
+			}
+		}
+	}
 }
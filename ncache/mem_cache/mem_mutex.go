@@ -0,0 +1,62 @@
+package mencache
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// 进程内的Key锁,与RedisMutex的语义一致
+type MemMutex struct {
+	memCacheService *MemCacheService
+	//命名一个名字
+	lockkey string
+	//锁的值
+	lockvalue string
+	//最多可以获取锁的时间，超过自动解锁
+	expiry time.Duration
+	//失败最多获取锁的次数
+	tries int
+	//获取锁失败后等待多少时间后重试
+	delay time.Duration
+}
+
+// 创建进程内的Key锁
+func MemNewMutex(lockkey, lockvalue string, memCacheService *MemCacheService, expiry time.Duration, tries int, delay time.Duration) *MemMutex {
+	return &MemMutex{
+		memCacheService: memCacheService,
+		lockkey:         lockkey,
+		lockvalue:       lockvalue,
+		expiry:          expiry,
+		tries:           tries,
+		delay:           delay,
+	}
+}
+
+// 获取锁,失败后等待delay重试,最多重试tries次
+func (m *MemMutex) MemLock() bool {
+	for curTries := 1; ; curTries++ {
+		if err := m.memCacheService.putNx(m.lockkey, m.lockvalue, m.expiry); err == nil {
+			return true
+		}
+		if curTries > m.tries {
+			slog.Error(fmt.Sprintf("[%v-%v]获取内存锁失败,当前第%d次获取,总次数%d", m.lockkey, m.lockvalue, curTries, m.tries))
+			return false
+		}
+		slog.Debug(fmt.Sprintf("[%v-%v]第%d次获取锁失败,等待%dms后重试", m.lockkey, m.lockvalue, curTries, m.delay.Milliseconds()))
+		time.Sleep(m.delay)
+	}
+}
+
+// 释放锁,只有锁的值与当前一致时才会删除
+func (m *MemMutex) MemReleseLock() bool {
+	service := m.memCacheService
+	service.nmu.Lock()
+	defer service.nmu.Unlock()
+	val, found := service.Cache.Get(m.lockkey)
+	if !found || val != m.lockvalue {
+		return false
+	}
+	service.Cache.Delete(m.lockkey)
+	return true
+}
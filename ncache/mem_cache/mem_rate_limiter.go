@@ -0,0 +1,122 @@
+package mencache
+
+import (
+	"math"
+	"time"
+
+	"github.com/niexqc/nlibs/nerror"
+)
+
+type memTokenBucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+type memSlidingWindow struct {
+	hits []time.Time
+}
+
+// 令牌桶限流
+// ratePerSecond 每秒生成的令牌数
+// capacity 桶容量,即允许的突发请求数
+// cost 本次消耗的令牌数
+// 返回是否通过,剩余令牌数,未通过时需等待的时间
+func (service *MemCacheService) TokenBucketAllow(key string, ratePerSecond float64, capacity, cost int64) (allowed bool, remaining int64, retryAfter time.Duration, err error) {
+	if ratePerSecond <= 0 {
+		return false, 0, 0, nerror.NewRunTimeError("令牌生成速率必须大于0")
+	}
+	service.nmu.Lock()
+	defer service.nmu.Unlock()
+	now := time.Now()
+	bucket := &memTokenBucket{tokens: float64(capacity), ts: now}
+	if val, found := service.Cache.Get(key); found {
+		if v, ok := val.(*memTokenBucket); ok {
+			bucket = v
+		} else {
+			return false, 0, 0, nerror.NewRunTimeError("限流的值不是令牌桶")
+		}
+	}
+	elapsed := max(0, now.Sub(bucket.ts).Seconds())
+	bucket.tokens = math.Min(float64(capacity), bucket.tokens+elapsed*ratePerSecond)
+	bucket.ts = now
+	if bucket.tokens >= float64(cost) {
+		bucket.tokens -= float64(cost)
+		allowed = true
+	} else {
+		retryAfter = time.Duration(math.Ceil((float64(cost)-bucket.tokens)/ratePerSecond*1000)) * time.Millisecond
+	}
+	expire := time.Duration(float64(capacity)/ratePerSecond*float64(time.Second)) + time.Second
+	service.Cache.Set(key, bucket, expire)
+	service.addKeyIdx(key)
+	return allowed, int64(bucket.tokens), retryAfter, nil
+}
+
+// 滑动窗口限流
+// limit 窗口内最多允许的次数
+// window 窗口大小
+// 返回是否通过,窗口内剩余次数,未通过时需等待的时间
+func (service *MemCacheService) SlidingWindowAllow(key string, limit int64, window time.Duration) (allowed bool, remaining int64, retryAfter time.Duration, err error) {
+	service.nmu.Lock()
+	defer service.nmu.Unlock()
+	now := time.Now()
+	sw := &memSlidingWindow{}
+	if val, found := service.Cache.Get(key); found {
+		if v, ok := val.(*memSlidingWindow); ok {
+			sw = v
+		} else {
+			return false, 0, 0, nerror.NewRunTimeError("限流的值不是滑动窗口")
+		}
+	}
+	// 移除窗口外的记录
+	start := 0
+	for start < len(sw.hits) && now.Sub(sw.hits[start]) >= window {
+		start++
+	}
+	sw.hits = sw.hits[start:]
+	count := int64(len(sw.hits))
+	if count < limit {
+		sw.hits = append(sw.hits, now)
+		service.Cache.Set(key, sw, window)
+		service.addKeyIdx(key)
+		return true, limit - count - 1, 0, nil
+	}
+	return false, 0, sw.hits[0].Add(window).Sub(now), nil
+}
+
+// 内存限流器
+type MemRateLimiter struct {
+	memCacheService *MemCacheService
+	//限流Key的前缀
+	keyPrefix string
+	//是否为滑动窗口,否则为令牌桶
+	slidingWindow bool
+	//令牌桶:每秒生成的令牌数
+	ratePerSecond float64
+	//令牌桶:桶容量;滑动窗口:窗口内最多允许的次数
+	limit int64
+	//滑动窗口:窗口大小
+	window time.Duration
+}
+
+// 创建令牌桶限流器
+func (service *MemCacheService) NewTokenBucketLimiter(keyPrefix string, ratePerSecond float64, capacity int64) *MemRateLimiter {
+	return &MemRateLimiter{memCacheService: service, keyPrefix: keyPrefix, ratePerSecond: ratePerSecond, limit: capacity}
+}
+
+// 创建滑动窗口限流器
+func (service *MemCacheService) NewSlidingWindowLimiter(keyPrefix string, limit int64, window time.Duration) *MemRateLimiter {
+	return &MemRateLimiter{memCacheService: service, keyPrefix: keyPrefix, slidingWindow: true, limit: limit, window: window}
+}
+
+// 判断key本次请求是否通过,未通过时返回需等待的时间
+func (l *MemRateLimiter) Allow(key string) (bool, time.Duration, error) {
+	var allowed bool
+	var retryAfter time.Duration
+	var err error
+	if l.slidingWindow {
+		allowed, _, retryAfter, err = l.memCacheService.SlidingWindowAllow(l.keyPrefix+key, l.limit, l.window)
+	} else {
+		allowed, _, retryAfter, err = l.memCacheService.TokenBucketAllow(l.keyPrefix+key, l.ratePerSecond, l.limit, 1)
+	}
+	return allowed, retryAfter, err
+}
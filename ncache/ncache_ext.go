@@ -1,6 +1,7 @@
 package ncache
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -12,42 +13,126 @@ import (
 	"github.com/patrickmn/go-cache"
 )
 
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
 // 创建RedisService
 func NewRedisService(redisPool *redis.Pool) *rediscache.RedisService {
 	return &rediscache.RedisService{RedisPool: redisPool}
 }
 
+// 根据配置的部署模式创建RedisService
+func NewRedisServiceByConf(conf *nyaml.YamlConfRedis) (*rediscache.RedisService, error) {
+	if conf.RedisMode == RedisModeCluster {
+		clusterPool, err := NewRedisClusterPool(conf)
+		if err != nil {
+			return nil, err
+		}
+		return &rediscache.RedisService{ClusterPool: clusterPool}, nil
+	}
+	return NewRedisService(NewRedisPool(conf)), nil
+}
+
 // 创建Redis连接池
+// sentinel模式下通过Sentinel发现主节点,其他模式连接RedisHost:RedisPort
 func NewRedisPool(conf *nyaml.YamlConfRedis) *redis.Pool {
+	if conf.RedisMode == RedisModeSentinel {
+		return NewRedisSentinelPool(conf)
+	}
 	address := fmt.Sprintf("%s:%d", conf.RedisHost, conf.RedisPort)
+	slog.Debug(address)
+	return newRedisPoolByAddr(conf, address)
+}
+
+func newRedisPoolByAddr(conf *nyaml.YamlConfRedis, address string) *redis.Pool {
+	dialOptions := redisDialOptions(conf)
+	return newRedisPoolWithDial(conf, func() (redis.Conn, error) {
+		return redis.Dial("tcp", address, dialOptions...)
+	})
+}
 
+func redisDialOptions(conf *nyaml.YamlConfRedis) []redis.DialOption {
 	dbOption := redis.DialDatabase(conf.DataBaseIdx)
 	pwOption := redis.DialPassword(conf.RedisPwd)
 	// **重要** 设置读写超时
 	conTimeout := redis.DialConnectTimeout(time.Second * time.Duration(conf.ConnectTimeout))
 	readTimeout := redis.DialReadTimeout(time.Second * time.Duration(conf.ReadTimeout))
 	writeTimeout := redis.DialWriteTimeout(time.Second * time.Duration(conf.WriteTimeout))
+	return []redis.DialOption{dbOption, pwOption, readTimeout, writeTimeout, conTimeout}
+}
 
+func newRedisPoolWithDial(conf *nyaml.YamlConfRedis, dial func() (redis.Conn, error)) *redis.Pool {
 	// 建立连接池
-	redisPool := &redis.Pool{
+	return &redis.Pool{
 		// 从配置文件获取maxidle以及maxactive，取不到则用后面的默认值
 		MaxIdle:     conf.MaxIdle,
 		MaxActive:   conf.MaxActive,
 		IdleTimeout: time.Duration(conf.IdleTimeout) * time.Second,
 		//如果空闲列表中没有可用的连接,且当前Active连接数 < MaxActive,则等待
 		Wait: true,
-		Dial: func() (redis.Conn, error) {
-			conn, err := redis.Dial("tcp", address, dbOption, pwOption, readTimeout, writeTimeout, conTimeout)
-			if err != nil {
-				return nil, err
-			}
-			return conn, nil
+		Dial: dial,
+	}
+}
+
+// 取出空闲超过该时间的连接时检查节点角色
+const redisSentinelRoleCheckIdle = 5 * time.Second
+
+// 创建Sentinel模式的Redis连接池
+// 每次新建连接时向Sentinel询问主节点地址;取出空闲超过redisSentinelRoleCheckIdle的连接时检查节点角色,
+// 故障转移后旧主节点的连接会被丢弃,活跃连接仍可能在检查前收到READONLY错误
+func NewRedisSentinelPool(conf *nyaml.YamlConfRedis) *redis.Pool {
+	sentinel := &rediscache.RedisSentinel{
+		Addrs:      append([]string{}, conf.SentinelAddrs...),
+		MasterName: conf.SentinelMaster,
+		DialOptions: []redis.DialOption{
+			redis.DialPassword(conf.SentinelPwd),
+			redis.DialConnectTimeout(time.Second * time.Duration(conf.ConnectTimeout)),
+			redis.DialReadTimeout(time.Second * time.Duration(conf.ReadTimeout)),
+			redis.DialWriteTimeout(time.Second * time.Duration(conf.WriteTimeout)),
 		},
 	}
-	slog.Debug(address + " " + conf.RedisPwd)
+	dialOptions := redisDialOptions(conf)
+	redisPool := newRedisPoolWithDial(conf, func() (redis.Conn, error) {
+		address, err := sentinel.MasterAddr()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := redis.Dial("tcp", address, dialOptions...)
+		if err != nil {
+			return nil, err
+		}
+		if !rediscache.RedisTestRole(conn, "master") {
+			conn.Close()
+			return nil, errors.New(address + "不是主节点")
+		}
+		return conn, nil
+	})
+	redisPool.TestOnBorrow = func(conn redis.Conn, lastUsed time.Time) error {
+		if time.Since(lastUsed) < redisSentinelRoleCheckIdle {
+			return nil
+		}
+		if !rediscache.RedisTestRole(conn, "master") {
+			return errors.New("连接的节点已不是主节点")
+		}
+		return nil
+	}
+	slog.Debug(fmt.Sprintf("Sentinel:%v Master:%s", conf.SentinelAddrs, conf.SentinelMaster))
 	return redisPool
 }
 
+// 创建Cluster模式的Redis连接池,每个主节点使用独立的连接池,集群模式不支持DataBaseIdx
+func NewRedisClusterPool(conf *nyaml.YamlConfRedis) (*rediscache.RedisClusterPool, error) {
+	clusterConf := *conf
+	clusterConf.DataBaseIdx = 0
+	slog.Debug(fmt.Sprintf("Cluster:%v", conf.ClusterAddrs))
+	return rediscache.NewRedisClusterPool(conf.ClusterAddrs, func(addr string) *redis.Pool {
+		return newRedisPoolByAddr(&clusterConf, addr)
+	})
+}
+
 // 创建MemCacheService
 // 默认永不过期，5分钟淘汰一次的缓存
 // cleanupInterval  5*time.Minute
@@ -0,0 +1,355 @@
+package rediscache
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/niexqc/nlibs/nerror"
+)
+
+const redisClusterSlots = 16384
+
+// 最多跟随MOVED/ASK重定向的次数
+const redisClusterMaxRedirects = 3
+
+// Redis集群连接池
+// 按Key所在的槽路由到对应主节点,收到MOVED时刷新槽位映射并重试,收到ASK时临时跳转
+// 一个连接在第一次执行带Key的命令时绑定节点,后续命令都发往该节点,多Key命令需保证Key在同一个槽(使用HashTag)
+type RedisClusterPool struct {
+	seedAddrs []string
+	newPool   func(addr string) *redis.Pool
+	mu        sync.RWMutex
+	pools     map[string]*redis.Pool
+	slots     [redisClusterSlots]string
+	//上次刷新槽位的时间
+	refreshTime time.Time
+}
+
+// 创建集群连接池
+// seedAddrs 种子节点 host:port
+// newPool 根据节点地址创建单节点连接池
+func NewRedisClusterPool(seedAddrs []string, newPool func(addr string) *redis.Pool) (*RedisClusterPool, error) {
+	if len(seedAddrs) == 0 {
+		return nil, nerror.NewRunTimeError("Redis集群种子节点不能为空")
+	}
+	cp := &RedisClusterPool{seedAddrs: seedAddrs, newPool: newPool, pools: map[string]*redis.Pool{}}
+	if err := cp.RefreshSlots(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// 刷新槽位与节点的映射
+func (cp *RedisClusterPool) RefreshSlots() error {
+	cp.mu.RLock()
+	addrs := append([]string{}, cp.seedAddrs...)
+	for addr := range cp.pools {
+		addrs = append(addrs, addr)
+	}
+	cp.mu.RUnlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn := cp.poolByAddr(addr).Get()
+		reply, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var slots [redisClusterSlots]string
+		for _, item := range reply {
+			slotInfo, err := redis.Values(item, nil)
+			if err != nil || len(slotInfo) < 3 {
+				continue
+			}
+			start, _ := redis.Int(slotInfo[0], nil)
+			end, _ := redis.Int(slotInfo[1], nil)
+			master, err := redis.Values(slotInfo[2], nil)
+			if err != nil || len(master) < 2 {
+				continue
+			}
+			host, _ := redis.String(master[0], nil)
+			port, _ := redis.Int(master[1], nil)
+			// 节点未配置cluster-announce-ip时返回空,使用当前连接的地址
+			if host == "" {
+				host = addr[:strings.LastIndex(addr, ":")]
+			}
+			for slot := start; slot <= end && slot < redisClusterSlots; slot++ {
+				slots[slot] = fmt.Sprintf("%s:%d", host, port)
+			}
+		}
+		cp.mu.Lock()
+		cp.slots = slots
+		cp.refreshTime = time.Now()
+		cp.mu.Unlock()
+		return nil
+	}
+	return nerror.NewRunTimeErrorWithError("获取Redis集群槽位失败", lastErr)
+}
+
+func (cp *RedisClusterPool) poolByAddr(addr string) *redis.Pool {
+	cp.mu.RLock()
+	pool, ok := cp.pools[addr]
+	cp.mu.RUnlock()
+	if ok {
+		return pool
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if pool, ok = cp.pools[addr]; !ok {
+		pool = cp.newPool(addr)
+		cp.pools[addr] = pool
+	}
+	return pool
+}
+
+func (cp *RedisClusterPool) addrByKey(key string) string {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	if key != "" {
+		if addr := cp.slots[RedisClusterSlot(key)]; addr != "" {
+			return addr
+		}
+	}
+	masters := cp.mastersLocked()
+	if len(masters) > 0 {
+		return masters[rand.IntN(len(masters))]
+	}
+	return cp.seedAddrs[0]
+}
+
+func (cp *RedisClusterPool) mastersLocked() []string {
+	set := map[string]struct{}{}
+	masters := []string{}
+	for _, addr := range cp.slots {
+		if _, ok := set[addr]; !ok && addr != "" {
+			set[addr] = struct{}{}
+			masters = append(masters, addr)
+		}
+	}
+	return masters
+}
+
+// 所有主节点的地址
+func (cp *RedisClusterPool) Masters() []string {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	return cp.mastersLocked()
+}
+
+// 获取指定节点的连接
+func (cp *RedisClusterPool) GetByAddr(addr string) redis.Conn {
+	return cp.poolByAddr(addr).Get()
+}
+
+// 获取连接,连接在第一次执行带Key的命令时绑定节点
+func (cp *RedisClusterPool) Get() redis.Conn {
+	return &redisClusterConn{clusterPool: cp}
+}
+
+// 关闭所有节点的连接池
+func (cp *RedisClusterPool) Close() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	var lastErr error
+	for _, pool := range cp.pools {
+		if err := pool.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// 收到MOVED后刷新槽位,1秒内只刷新一次
+func (cp *RedisClusterPool) refreshOnMoved() {
+	cp.mu.RLock()
+	recent := time.Since(cp.refreshTime) < time.Second
+	cp.mu.RUnlock()
+	if recent {
+		return
+	}
+	if err := cp.RefreshSlots(); err != nil {
+		slog.Warn(fmt.Sprintf("刷新Redis集群槽位失败:%v", err))
+	}
+}
+
+type redisClusterConn struct {
+	clusterPool *RedisClusterPool
+	conn        redis.Conn
+	closed      bool
+}
+
+func (c *redisClusterConn) bind(cmd string, args []any) redis.Conn {
+	if c.conn == nil {
+		c.conn = c.clusterPool.poolByAddr(c.clusterPool.addrByKey(redisClusterCmdKey(cmd, args))).Get()
+	}
+	return c.conn
+}
+
+func (c *redisClusterConn) Do(cmd string, args ...any) (any, error) {
+	return c.doWithRedirect(func(conn redis.Conn) (any, error) {
+		return conn.Do(cmd, args...)
+	}, cmd, args)
+}
+
+func (c *redisClusterConn) DoWithTimeout(timeout time.Duration, cmd string, args ...any) (any, error) {
+	return c.doWithRedirect(func(conn redis.Conn) (any, error) {
+		return redis.DoWithTimeout(conn, timeout, cmd, args...)
+	}, cmd, args)
+}
+
+func (c *redisClusterConn) doWithRedirect(do func(conn redis.Conn) (any, error), cmd string, args []any) (any, error) {
+	if c.closed {
+		return nil, nerror.NewRunTimeError("Redis集群连接已关闭")
+	}
+	reply, err := do(c.bind(cmd, args))
+	for i := 0; i < redisClusterMaxRedirects; i++ {
+		redisErr, ok := err.(redis.Error)
+		if !ok {
+			return reply, err
+		}
+		parts := strings.Fields(redisErr.Error())
+		if len(parts) != 3 || (parts[0] != "MOVED" && parts[0] != "ASK") {
+			return reply, err
+		}
+		addr := parts[2]
+		if parts[0] == "MOVED" {
+			go c.clusterPool.refreshOnMoved()
+			c.conn.Close()
+			c.conn = c.clusterPool.poolByAddr(addr).Get()
+			reply, err = do(c.conn)
+			continue
+		}
+		// ASK 只对本次命令有效
+		askConn := c.clusterPool.poolByAddr(addr).Get()
+		if _, aerr := askConn.Do("ASKING"); aerr != nil {
+			askConn.Close()
+			return nil, aerr
+		}
+		reply, err = do(askConn)
+		askConn.Close()
+	}
+	return reply, err
+}
+
+func (c *redisClusterConn) Send(cmd string, args ...any) error {
+	if c.closed {
+		return nerror.NewRunTimeError("Redis集群连接已关闭")
+	}
+	return c.bind(cmd, args).Send(cmd, args...)
+}
+
+func (c *redisClusterConn) Flush() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Flush()
+}
+
+func (c *redisClusterConn) Receive() (any, error) {
+	if c.conn == nil {
+		return nil, nerror.NewRunTimeError("Redis集群连接未绑定节点")
+	}
+	return c.conn.Receive()
+}
+
+func (c *redisClusterConn) ReceiveWithTimeout(timeout time.Duration) (any, error) {
+	if c.conn == nil {
+		return nil, nerror.NewRunTimeError("Redis集群连接未绑定节点")
+	}
+	return redis.ReceiveWithTimeout(c.conn, timeout)
+}
+
+func (c *redisClusterConn) Err() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Err()
+}
+
+func (c *redisClusterConn) Close() error {
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// 获取命令中用于路由的Key,无Key时返回空
+func redisClusterCmdKey(cmd string, args []any) string {
+	switch strings.ToUpper(cmd) {
+	case "EVAL", "EVALSHA":
+		if len(args) > 2 {
+			if numKeys, _ := redis.Int(args[1], nil); numKeys > 0 {
+				return redisArg2Str(args[2])
+			}
+		}
+		return ""
+	case "XREAD", "XREADGROUP":
+		for i, arg := range args {
+			if strings.EqualFold(redisArg2Str(arg), "STREAMS") && i+1 < len(args) {
+				return redisArg2Str(args[i+1])
+			}
+		}
+		return ""
+	case "XGROUP", "XINFO":
+		if len(args) > 1 {
+			return redisArg2Str(args[1])
+		}
+		return ""
+	case "PING", "INFO", "SCAN", "CLUSTER", "ROLE", "SCRIPT", "ASKING", "READONLY":
+		return ""
+	}
+	if len(args) > 0 {
+		return redisArg2Str(args[0])
+	}
+	return ""
+}
+
+func redisArg2Str(arg any) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// 计算Key所在的槽,支持HashTag {tag}
+func RedisClusterSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % redisClusterSlots)
+}
+
+// CRC16/XMODEM
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
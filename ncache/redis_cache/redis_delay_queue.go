@@ -0,0 +1,245 @@
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+// 基于Redis有序集合的延时队列
+// 任务按到期时间存入ZSET,到期后由Lua脚本原子取出并延后至可见超时时间;
+// 处理成功后删除任务,处理失败或进程崩溃时任务在可见超时后被再次取出(至少一次)
+// Redis集群下queueKey与死信队列Key需使用相同的HashTag,如 {order}:timeout
+type RedisDelayQueue struct {
+	redisService *RedisService
+	//任务ZSET的Key
+	queueKey string
+	//任务内容HASH的Key
+	jobsKey string
+	//投递次数HASH的Key
+	attemptsKey string
+	//死信队列的Key
+	deadLetterKey string
+	//取出后多长时间未确认则重新投递
+	visibilityTimeout time.Duration
+	//没有到期任务时的轮询间隔
+	pollInterval time.Duration
+	//每次最多取出的任务数量
+	batchSize int
+	//最多投递次数,超过后转入死信队列,0表示不限制
+	maxAttempts int64
+}
+
+// 延时任务
+type RedisDelayJob struct {
+	Id       string
+	Body     string
+	Attempts int64
+	//本次取出时设置的分数(可见超时时间毫秒),确认时用于识别本次投递
+	Score int64
+}
+
+// 创建延时队列
+// 默认可见超时60秒,轮询间隔1秒,每次取出10个,不限制投递次数
+func RedisNewDelayQueue(queueKey string, redisService *RedisService, options ...RedisDelayQueueOption) *RedisDelayQueue {
+	queue := &RedisDelayQueue{
+		redisService:      redisService,
+		queueKey:          queueKey,
+		jobsKey:           queueKey + ":jobs",
+		attemptsKey:       queueKey + ":attempts",
+		deadLetterKey:     queueKey + ":dead",
+		visibilityTimeout: 60 * time.Second,
+		pollInterval:      time.Second,
+		batchSize:         10,
+	}
+	for _, option := range options {
+		option.Apply(queue)
+	}
+	return queue
+}
+
+// 配置延时队列
+type RedisDelayQueueOption interface {
+	Apply(*RedisDelayQueue)
+}
+
+// 延时队列的配置方法
+type DelayQueueOptionFunc func(*RedisDelayQueue)
+
+// Apply 实现RedisDelayQueueOption.Apply
+func (f DelayQueueOptionFunc) Apply(queue *RedisDelayQueue) {
+	f(queue)
+}
+
+// 设置取出后多长时间未确认则重新投递
+func RedisDelayQueueSetVisibilityTimeout(visibilityTimeout time.Duration) RedisDelayQueueOption {
+	return DelayQueueOptionFunc(func(q *RedisDelayQueue) {
+		q.visibilityTimeout = visibilityTimeout
+	})
+}
+
+// 设置没有到期任务时的轮询间隔
+func RedisDelayQueueSetPollInterval(pollInterval time.Duration) RedisDelayQueueOption {
+	return DelayQueueOptionFunc(func(q *RedisDelayQueue) {
+		q.pollInterval = pollInterval
+	})
+}
+
+// 设置每次最多取出的任务数量
+func RedisDelayQueueSetBatchSize(batchSize int) RedisDelayQueueOption {
+	return DelayQueueOptionFunc(func(q *RedisDelayQueue) {
+		q.batchSize = batchSize
+	})
+}
+
+// 设置最多投递次数,超过后转入死信队列
+func RedisDelayQueueSetMaxAttempts(maxAttempts int64) RedisDelayQueueOption {
+	return DelayQueueOptionFunc(func(q *RedisDelayQueue) {
+		q.maxAttempts = maxAttempts
+	})
+}
+
+// 设置死信队列的Key
+func RedisDelayQueueSetDeadLetterKey(deadLetterKey string) RedisDelayQueueOption {
+	return DelayQueueOptionFunc(func(q *RedisDelayQueue) {
+		q.deadLetterKey = deadLetterKey
+	})
+}
+
+// 延时delay后执行,jobId为空时自动生成,相同jobId会覆盖之前的任务
+func (q *RedisDelayQueue) PushDelay(jobId, body string, delay time.Duration) (string, error) {
+	return q.PushAt(jobId, body, time.Now().Add(delay))
+}
+
+// 在dueTime执行,jobId为空时自动生成,相同jobId会覆盖之前的任务
+func (q *RedisDelayQueue) PushAt(jobId, body string, dueTime time.Time) (string, error) {
+	if jobId == "" {
+		jobId = ntools.UUIDStr(false)
+	}
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	_, err := RdisScriptDelayPush.Do(conn, q.queueKey, q.jobsKey, q.attemptsKey, jobId, dueTime.UnixMilli(), body)
+	if err != nil {
+		return "", err
+	}
+	return jobId, nil
+}
+
+// 取消任务,返回任务是否存在
+func (q *RedisDelayQueue) Cancel(jobId string) (bool, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	num, err := redis.Int(RdisScriptDelayRemove.Do(conn, q.queueKey, q.jobsKey, q.attemptsKey, jobId))
+	return num > 0, err
+}
+
+// 确认任务已处理完成,返回是否删除
+// 任务在处理期间被重新写入或已超时被再次取出时不删除
+func (q *RedisDelayQueue) Ack(job *RedisDelayJob) (bool, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	num, err := redis.Int(RdisScriptDelayAck.Do(conn, q.queueKey, q.jobsKey, q.attemptsKey, job.Id, job.Score))
+	return num > 0, err
+}
+
+// 队列中的任务数量(包含未到期和处理中的)
+func (q *RedisDelayQueue) Size() (int64, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	return redis.Int64(conn.Do("ZCARD", q.queueKey))
+}
+
+// 取出到期的任务
+func (q *RedisDelayQueue) PopDue() ([]*RedisDelayJob, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	now := time.Now()
+	score := now.Add(q.visibilityTimeout).UnixMilli()
+	reply, err := redis.Values(RdisScriptDelayPopDue.Do(conn, q.queueKey, q.jobsKey, q.attemptsKey,
+		now.UnixMilli(), q.batchSize, score))
+	if err != nil {
+		return nil, err
+	}
+	if len(reply)%3 != 0 {
+		return nil, nerror.NewRunTimeError("延时队列返回格式错误")
+	}
+	jobs := make([]*RedisDelayJob, 0, len(reply)/3)
+	for i := 0; i < len(reply); i += 3 {
+		id, _ := redis.String(reply[i], nil)
+		body, _ := redis.String(reply[i+1], nil)
+		attempts, _ := redis.Int64(reply[i+2], nil)
+		jobs = append(jobs, &RedisDelayJob{Id: id, Body: body, Attempts: attempts, Score: score})
+	}
+	return jobs, nil
+}
+
+// 消费到期任务,阻塞直到ctx被取消
+// handler返回nil时删除任务;返回错误或panic时任务在可见超时后重新投递
+func (q *RedisDelayQueue) Consume(ctx context.Context, handler func(job *RedisDelayJob) error) {
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug(fmt.Sprintf("延时队列[%s]消费已停止", q.queueKey))
+			return
+		default:
+		}
+		jobs, err := q.PopDue()
+		if err != nil {
+			slog.Warn(fmt.Sprintf("延时队列[%s]取出任务失败: %v, 3秒后重试中", q.queueKey, err))
+			q.sleep(ctx, 3*time.Second)
+			continue
+		}
+		if len(jobs) == 0 {
+			q.sleep(ctx, q.pollInterval)
+			continue
+		}
+		for _, job := range jobs {
+			if q.maxAttempts > 0 && job.Attempts > q.maxAttempts {
+				q.moveToDeadLetter(job)
+				continue
+			}
+			if herr := q.handleJob(job, handler); herr != nil {
+				slog.Warn(fmt.Sprintf("延时队列[%s]任务[%s]第%d次处理失败: %v", q.queueKey, job.Id, job.Attempts, herr))
+				continue
+			}
+			if _, aerr := q.Ack(job); aerr != nil {
+				slog.Error(fmt.Sprintf("延时队列[%s]任务[%s]确认失败: %v", q.queueKey, job.Id, aerr))
+			}
+		}
+	}
+}
+
+func (q *RedisDelayQueue) handleJob(job *RedisDelayJob, handler func(job *RedisDelayJob) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = nerror.NewRunTimeErrorFmt("处理任务发生异常:%v", r)
+		}
+	}()
+	return handler(job)
+}
+
+// 在同一脚本中写入死信并删除任务
+func (q *RedisDelayQueue) moveToDeadLetter(job *RedisDelayJob) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	moved, err := redis.Int(RdisScriptDelayDeadLetter.Do(conn, q.queueKey, q.jobsKey, q.attemptsKey, q.deadLetterKey, job.Id, job.Score))
+	if err != nil {
+		slog.Error(fmt.Sprintf("延时队列[%s]任务[%s]写入死信队列失败: %v", q.queueKey, job.Id, err))
+		return
+	}
+	if moved > 0 {
+		slog.Warn(fmt.Sprintf("延时队列[%s]任务[%s]已投递%d次,转入死信队列[%s]", q.queueKey, job.Id, job.Attempts-1, q.deadLetterKey))
+	}
+}
+
+func (q *RedisDelayQueue) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -1,12 +1,15 @@
 package rediscache
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/niexqc/nlibs/nerror"
 )
 
 // Mutex 分布式锁
@@ -24,36 +27,156 @@ type RedisMutex struct {
 	delay time.Duration
 	//当前尝试了多少次
 	curTries int
+	//是否可重入,可重入时以lockvalue作为持有者,同一持有者可多次加锁,需解锁相同次数
+	//可重入锁在Redis中以HASH存储,同一个lockkey不能混用可重入与不可重入
+	reentrant bool
+	//是否开启看门狗,持有锁期间自动续期
+	watchdog bool
+	//看门狗续期间隔,默认为expiry的1/3
+	watchdogInterval time.Duration
+	//当前对象持有锁的次数
+	holds  int
+	holdMu sync.Mutex
+	//看门狗停止信号
+	watchdogStop chan struct{}
 }
 
 // Lock ...
 func (m *RedisMutex) RedisLock() bool {
-	err := m.redisService.PutNxExStr(m.lockkey, m.lockvalue, int(m.expiry.Seconds()))
-	if nil != err {
-		if netError, ok := err.(net.Error); ok {
-			slog.Error(fmt.Sprintf("[%v-%v]获取Redis锁失败,%s", m.lockkey, m.lockvalue, netError.Error()))
+	for {
+		ok, err := m.tryLock()
+		if nil != err {
+			slog.Error(fmt.Sprintf("[%v-%v]获取Redis锁失败,%s", m.lockkey, m.lockvalue, err.Error()))
 			return false
 		}
+		if ok {
+			m.curTries = 1
+			m.onLocked()
+			return true
+		}
 		if m.curTries > m.tries {
 			slog.Error(fmt.Sprintf("[%v-%v]获取Redis锁失败,当前第%d次获取,总次数%d", m.lockkey, m.lockvalue, m.curTries, m.tries))
+			m.curTries = 1
 			return false
 		}
 		slog.Debug(fmt.Sprintf("[%v-%v]第%d次获取锁失败,等待%dms后重试", m.lockkey, m.lockvalue, m.curTries, time.Duration(m.delay).Milliseconds()))
 		m.curTries++
 		time.Sleep(m.delay)
-		return m.RedisLock()
 	}
-	return true
+}
+
+// 获取锁,直到成功或ctx被取消,每次失败后等待delay重试,忽略tries
+func (m *RedisMutex) RedisLockCtx(ctx context.Context) error {
+	for {
+		ok, err := m.tryLock()
+		if nil != err {
+			return nerror.NewRunTimeErrorWithError(fmt.Sprintf("[%v-%v]获取Redis锁失败", m.lockkey, m.lockvalue), err)
+		}
+		if ok {
+			m.onLocked()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nerror.NewRunTimeErrorWithError(fmt.Sprintf("[%v-%v]等待Redis锁已取消", m.lockkey, m.lockvalue), ctx.Err())
+		case <-time.After(m.delay):
+		}
+	}
+}
+
+// 尝试获取一次锁,只有网络错误时返回err
+func (m *RedisMutex) tryLock() (bool, error) {
+	if m.reentrant {
+		conn := m.redisService.GetConn()
+		defer conn.Close()
+		count, err := redis.Int(RdisScriptReentrantLock.Do(conn, m.lockkey, m.lockvalue, m.expiry.Milliseconds()))
+		if nil != err {
+			return false, err
+		}
+		return count > 0, nil
+	}
+	err := m.redisService.PutNxExStr(m.lockkey, m.lockvalue, int(m.expiry.Seconds()))
+	if nil != err {
+		if netError, ok := err.(net.Error); ok {
+			return false, netError
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (m *RedisMutex) onLocked() {
+	m.holdMu.Lock()
+	defer m.holdMu.Unlock()
+	m.holds++
+	if m.holds == 1 && m.watchdog {
+		m.startWatchdog()
+	}
 }
 
 // ReleseLock ...
+// 可重入锁需解锁与加锁相同的次数才会真正释放
 func (m *RedisMutex) RedisReleseLock() bool {
-	conn := m.redisService.RedisPool.Get()
+	m.holdMu.Lock()
+	defer m.holdMu.Unlock()
+	if m.holds > 0 {
+		m.holds--
+	}
+	if m.holds == 0 && m.watchdogStop != nil {
+		close(m.watchdogStop)
+		m.watchdogStop = nil
+	}
+	conn := m.redisService.GetConn()
 	defer conn.Close()
+	if m.reentrant {
+		count, err := redis.Int(RdisScriptReentrantUnlock.Do(conn, m.lockkey, m.lockvalue))
+		return err == nil && count >= 0
+	}
 	_, err := redis.Int(RdisScriptDelKv.Do(conn, m.lockkey, m.lockvalue))
 	return err == nil
 }
 
+// 续期一次,返回锁是否仍由当前持有者持有
+func (m *RedisMutex) RedisRenew() (bool, error) {
+	conn := m.redisService.GetConn()
+	defer conn.Close()
+	script := RdisScriptRenewKv
+	if m.reentrant {
+		script = RdisScriptReentrantRenew
+	}
+	num, err := redis.Int(script.Do(conn, m.lockkey, m.lockvalue, m.expiry.Milliseconds()))
+	return num > 0, err
+}
+
+func (m *RedisMutex) startWatchdog() {
+	interval := m.watchdogInterval
+	if interval <= 0 {
+		interval = m.expiry / 3
+	}
+	stop := make(chan struct{})
+	m.watchdogStop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ok, err := m.RedisRenew()
+				if nil != err {
+					slog.Warn(fmt.Sprintf("[%v-%v]Redis锁续期失败,%v", m.lockkey, m.lockvalue, err))
+					continue
+				}
+				if !ok {
+					slog.Error(fmt.Sprintf("[%v-%v]Redis锁已丢失,停止续期", m.lockkey, m.lockvalue))
+					return
+				}
+			}
+		}
+	}()
+}
+
 // NewMutex ...
 // 默认8秒过期，重试次数16次，失败500毫秒获取一次
 func RedisNewMutex(lockkey, lockvalue string, redisService *RedisService, options ...RedisMutexOption) *RedisMutex {
@@ -105,3 +228,18 @@ func RedisMutexSetDelay(delay time.Duration) RedisMutexOption {
 		m.delay = delay
 	})
 }
+
+// SetReentrant 设置是否可重入
+func RedisMutexSetReentrant(reentrant bool) RedisMutexOption {
+	return OptionFunc(func(m *RedisMutex) {
+		m.reentrant = reentrant
+	})
+}
+
+// SetWatchdog 开启看门狗,持有锁期间每interval续期一次,interval为0时使用expiry的1/3
+func RedisMutexSetWatchdog(interval time.Duration) RedisMutexOption {
+	return OptionFunc(func(m *RedisMutex) {
+		m.watchdog = true
+		m.watchdogInterval = interval
+	})
+}
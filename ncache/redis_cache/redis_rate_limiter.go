@@ -0,0 +1,83 @@
+package rediscache
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+// 令牌桶限流
+// ratePerSecond 每秒生成的令牌数
+// capacity 桶容量,即允许的突发请求数
+// cost 本次消耗的令牌数
+// 返回是否通过,剩余令牌数,未通过时需等待的时间
+func (service *RedisService) TokenBucketAllow(key string, ratePerSecond float64, capacity, cost int64) (allowed bool, remaining int64, retryAfter time.Duration, err error) {
+	if ratePerSecond <= 0 {
+		return false, 0, 0, nerror.NewRunTimeError("令牌生成速率必须大于0")
+	}
+	conn := service.GetConn()
+	defer conn.Close()
+	reply, err := redis.Int64s(RdisScriptTokenBucket.Do(conn, key, ratePerSecond/1000, capacity, time.Now().UnixMilli(), cost))
+	return parseRateLimitReply(reply, err)
+}
+
+// 滑动窗口限流
+// limit 窗口内最多允许的次数
+// window 窗口大小
+// 返回是否通过,窗口内剩余次数,未通过时需等待的时间
+func (service *RedisService) SlidingWindowAllow(key string, limit int64, window time.Duration) (allowed bool, remaining int64, retryAfter time.Duration, err error) {
+	conn := service.GetConn()
+	defer conn.Close()
+	reply, err := redis.Int64s(RdisScriptSlidingWindow.Do(conn, key, window.Milliseconds(), limit, time.Now().UnixMilli(), ntools.UUIDStr(false)))
+	return parseRateLimitReply(reply, err)
+}
+
+func parseRateLimitReply(reply []int64, err error) (bool, int64, time.Duration, error) {
+	if err != nil {
+		return false, 0, 0, err
+	}
+	if len(reply) != 3 {
+		return false, 0, 0, nerror.NewRunTimeError("限流脚本返回格式错误")
+	}
+	return reply[0] == 1, reply[1], time.Duration(reply[2]) * time.Millisecond, nil
+}
+
+// Redis分布式限流器
+type RedisRateLimiter struct {
+	redisService *RedisService
+	//限流Key的前缀
+	keyPrefix string
+	//是否为滑动窗口,否则为令牌桶
+	slidingWindow bool
+	//令牌桶:每秒生成的令牌数
+	ratePerSecond float64
+	//令牌桶:桶容量;滑动窗口:窗口内最多允许的次数
+	limit int64
+	//滑动窗口:窗口大小
+	window time.Duration
+}
+
+// 创建令牌桶限流器
+func (service *RedisService) NewTokenBucketLimiter(keyPrefix string, ratePerSecond float64, capacity int64) *RedisRateLimiter {
+	return &RedisRateLimiter{redisService: service, keyPrefix: keyPrefix, ratePerSecond: ratePerSecond, limit: capacity}
+}
+
+// 创建滑动窗口限流器
+func (service *RedisService) NewSlidingWindowLimiter(keyPrefix string, limit int64, window time.Duration) *RedisRateLimiter {
+	return &RedisRateLimiter{redisService: service, keyPrefix: keyPrefix, slidingWindow: true, limit: limit, window: window}
+}
+
+// 判断key本次请求是否通过,未通过时返回需等待的时间
+func (l *RedisRateLimiter) Allow(key string) (bool, time.Duration, error) {
+	var allowed bool
+	var retryAfter time.Duration
+	var err error
+	if l.slidingWindow {
+		allowed, _, retryAfter, err = l.redisService.SlidingWindowAllow(l.keyPrefix+key, l.limit, l.window)
+	} else {
+		allowed, _, retryAfter, err = l.redisService.TokenBucketAllow(l.keyPrefix+key, l.ratePerSecond, l.limit, 1)
+	}
+	return allowed, retryAfter, err
+}
@@ -18,5 +18,188 @@ end;
 return current
 `
 
+// 值匹配时续期 ARGV[1]-值 ARGV[2]-过期时间毫秒
+var s_renew_kv = `
+if redis.call('GET', KEYS[1])==ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+else
+	return 0
+end
+`
+
+// 可重入锁加锁,返回持有次数,0表示锁被其他持有者占用 ARGV[1]-持有者 ARGV[2]-过期时间毫秒
+var s_reentrant_lock = `
+if redis.call('EXISTS', KEYS[1]) == 0 or redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	local count = redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return count
+end
+return 0
+`
+
+// 可重入锁解锁,返回剩余持有次数,-1表示非当前持有者 ARGV[1]-持有者
+var s_reentrant_unlock = `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local count = redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+if count <= 0 then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+return count
+`
+
+// 可重入锁续期 ARGV[1]-持有者 ARGV[2]-过期时间毫秒
+var s_reentrant_renew = `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+else
+	return 0
+end
+`
+
 var RdisScriptDelKv = redis.NewScript(1, s_delkey_value)
 var RdisScriptIntIncr = redis.NewScript(1, s_int_incr)
+var RdisScriptRenewKv = redis.NewScript(1, s_renew_kv)
+var RdisScriptReentrantLock = redis.NewScript(1, s_reentrant_lock)
+var RdisScriptReentrantUnlock = redis.NewScript(1, s_reentrant_unlock)
+var RdisScriptReentrantRenew = redis.NewScript(1, s_reentrant_renew)
+
+// 延时队列写入 KEYS[1]-ZSET KEYS[2]-任务内容HASH KEYS[3]-投递次数HASH ARGV[1]-任务ID ARGV[2]-到期时间毫秒 ARGV[3]-任务内容
+var s_delay_push = `
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+`
+
+// 延时队列取出到期任务,并将分数延后至可见超时时间,未确认的任务超时后会被再次取出
+// ARGV[1]-当前时间毫秒 ARGV[2]-最多取出数量 ARGV[3]-可见超时后的时间毫秒
+var s_delay_pop_due = `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local result = {}
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[3], id)
+	local attempts = redis.call('HINCRBY', KEYS[3], id, 1)
+	local body = redis.call('HGET', KEYS[2], id)
+	if not body then
+		body = ''
+	end
+	table.insert(result, id)
+	table.insert(result, body)
+	table.insert(result, attempts)
+end
+return result
+`
+
+// 延时队列删除任务
+var s_delay_remove = `
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`
+
+// 延时队列确认任务,分数与取出时一致才删除,避免删除处理期间以相同ID重新写入的任务
+// ARGV[1]-任务ID ARGV[2]-取出时设置的分数
+var s_delay_ack = `
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`
+
+// 延时队列任务转入死信队列,校验分数后写入死信并删除任务
+// KEYS[4]-死信LIST ARGV[1]-任务ID ARGV[2]-取出时设置的分数
+var s_delay_dead_letter = `
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+local body = redis.call('HGET', KEYS[2], ARGV[1]) or ''
+redis.call('RPUSH', KEYS[4], body)
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`
+
+var RdisScriptDelayPush = redis.NewScript(3, s_delay_push)
+var RdisScriptDelayPopDue = redis.NewScript(3, s_delay_pop_due)
+var RdisScriptDelayRemove = redis.NewScript(3, s_delay_remove)
+var RdisScriptDelayAck = redis.NewScript(3, s_delay_ack)
+var RdisScriptDelayDeadLetter = redis.NewScript(4, s_delay_dead_letter)
+
+// 令牌桶限流,返回 {是否通过,剩余令牌,需等待毫秒}
+// ARGV[1]-每毫秒生成令牌数 ARGV[2]-桶容量 ARGV[3]-当前时间毫秒 ARGV[4]-本次消耗令牌数
+var s_token_bucket = `
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	wait = math.ceil((cost - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate) + 1000)
+return {allowed, math.floor(tokens), wait}
+`
+
+// 滑动窗口限流,返回 {是否通过,剩余次数,需等待毫秒}
+// ARGV[1]-窗口毫秒 ARGV[2]-窗口内最大次数 ARGV[3]-当前时间毫秒 ARGV[4]-本次请求唯一标识
+var s_sliding_window = `
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(oldest[2]) + window - now}
+`
+
+var RdisScriptTokenBucket = redis.NewScript(1, s_token_bucket)
+var RdisScriptSlidingWindow = redis.NewScript(1, s_sliding_window)
+
+// Stream消息转入死信队列,XACK成功后才写入死信,返回1表示已转入,0表示已被其他消费者确认
+// KEYS[1]-Stream KEYS[2]-死信Stream ARGV[1]-消费组 ARGV[2]-消息ID ARGV[3]-投递次数 ARGV[4]-转入时间 ARGV[5]-是否删除原消息(1/0)
+var s_stream_dead_letter = `
+if redis.call('XACK', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+local entries = redis.call('XRANGE', KEYS[1], ARGV[2], ARGV[2])
+if #entries > 0 then
+	local fields = entries[1][2]
+	local body = ''
+	for i = 1, #fields, 2 do
+		if fields[i] == 'body' then
+			body = fields[i + 1]
+		end
+	end
+	redis.call('XADD', KEYS[2], '*', 'body', body, 'srcId', ARGV[2], 'deliveryCount', ARGV[3], 'deadTime', ARGV[4])
+end
+if ARGV[5] == '1' then
+	redis.call('XDEL', KEYS[1], ARGV[2])
+end
+return 1
+`
+
+var RdisScriptStreamDeadLetter = redis.NewScript(2, s_stream_dead_letter)
@@ -0,0 +1,69 @@
+package rediscache
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/niexqc/nlibs/nerror"
+)
+
+// 通过Sentinel发现主节点
+type RedisSentinel struct {
+	//Sentinel地址 host:port
+	Addrs []string
+	//Sentinel监控的主节点名称
+	MasterName string
+	//连接Sentinel的参数
+	DialOptions []redis.DialOption
+	mu          sync.Mutex
+}
+
+// 依次询问Sentinel获取当前主节点地址,成功的Sentinel会被移到首位
+func (s *RedisSentinel) MasterAddr() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lastErr error
+	for i, sentinelAddr := range s.Addrs {
+		addr, err := s.queryMasterAddr(sentinelAddr)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Sentinel[%s]获取主节点[%s]失败:%v", sentinelAddr, s.MasterName, err))
+			lastErr = err
+			continue
+		}
+		if i > 0 {
+			s.Addrs[0], s.Addrs[i] = s.Addrs[i], s.Addrs[0]
+		}
+		return addr, nil
+	}
+	return "", nerror.NewRunTimeErrorWithError(fmt.Sprintf("所有Sentinel均无法获取主节点[%s]", s.MasterName), lastErr)
+}
+
+func (s *RedisSentinel) queryMasterAddr(sentinelAddr string) (string, error) {
+	conn, err := redis.Dial("tcp", sentinelAddr, s.DialOptions...)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.MasterName))
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", nerror.NewRunTimeError("Sentinel返回的主节点地址格式错误")
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+// 检查连接的节点角色是否为role(master|slave),用于发现故障转移后的旧连接
+func RedisTestRole(conn redis.Conn, role string) bool {
+	reply, err := redis.Values(conn.Do("ROLE"))
+	if err != nil || len(reply) == 0 {
+		return false
+	}
+	curRole, err := redis.String(reply[0], nil)
+	return err == nil && strings.EqualFold(curRole, role)
+}
@@ -1,6 +1,7 @@
 package rediscache
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -13,11 +14,35 @@ import (
 // RedisService ...
 type RedisService struct {
 	RedisPool *redis.Pool
+	//集群模式的连接池,设置后优先于RedisPool
+	ClusterPool *RedisClusterPool
+}
+
+// 获取连接,集群模式下从ClusterPool获取
+func (service *RedisService) GetConn() redis.Conn {
+	if service.ClusterPool != nil {
+		return service.ClusterPool.Get()
+	}
+	return service.RedisPool.Get()
+}
+
+// 发送PING,可用于就绪检查
+func (service *RedisService) Ping() error {
+	conn := service.GetConn()
+	defer conn.Close()
+	resp, err := redis.String(conn.Do("PING"))
+	if err != nil {
+		return err
+	}
+	if resp != "PONG" {
+		return fmt.Errorf("PING返回[%s]", resp)
+	}
+	return nil
 }
 
 // Int64自增
 func (service *RedisService) Int64Incr(key string, expireMillisecond int64) (num int64, err error) {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	resp, err := redis.Int64(RdisScriptIntIncr.Do(conn, key, expireMillisecond))
 	return resp, err
@@ -25,7 +50,7 @@ func (service *RedisService) Int64Incr(key string, expireMillisecond int64) (num
 
 // GetStr ...
 func (service *RedisService) GetStr(key string) (string, error) {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	val, err := redis.String(conn.Do("GET", key))
 	if err != nil {
@@ -36,7 +61,7 @@ func (service *RedisService) GetStr(key string) (string, error) {
 
 // PutStr ...
 func (service *RedisService) PutStr(key string, val string) error {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	resp, err := redis.String(conn.Do("SET", key, val))
 	if nil != err {
@@ -50,7 +75,7 @@ func (service *RedisService) PutStr(key string, val string) error {
 
 // EXISTS ...
 func (service *RedisService) Exist(key string) (bool, error) {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	val, err := redis.Int(conn.Do("EXISTS", key))
 	if err != nil {
@@ -67,7 +92,7 @@ func (service *RedisService) ExistWithoutErr(key string) bool {
 
 // 设置键值对并指定过期时间（​​原子性操作​​）,无论键是否存在，都会​​覆盖旧值​​并设置新的过期时间
 func (service *RedisService) PutExStr(key string, val string, sencond int) error {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	resp, err := redis.String(conn.Do("SETEX", key, sencond, val))
 	if nil != err {
@@ -81,7 +106,7 @@ func (service *RedisService) PutExStr(key string, val string, sencond int) error
 
 // 仅在键​​不存在​​时设置键值对（​​原子性操作​​）
 func (service *RedisService) PutNxExStr(key string, val string, sencond int) error {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	resp, err := redis.String(conn.Do("SET", key, val, "EX", sencond, "NX"))
 	if nil != err {
@@ -93,9 +118,23 @@ func (service *RedisService) PutNxExStr(key string, val string, sencond int) err
 	return nil
 }
 
+// 仅在key不存在时写入,key已存在返回false,err仅表示Redis执行失败
+func (service *RedisService) TryPutNxExStr(key string, val string, sencond int) (bool, error) {
+	conn := service.GetConn()
+	defer conn.Close()
+	_, err := redis.String(conn.Do("SET", key, val, "EX", sencond, "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // KeySetExpire ...
 func (service *RedisService) KeySetExpire(key string, sencond int) error {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	resp, err := redis.Int64(conn.Do("EXPIRE", key, sencond))
 	if err != nil {
@@ -111,7 +150,7 @@ func (service *RedisService) KeySetExpire(key string, sencond int) error {
 
 // ClearKey 清理KEY
 func (service *RedisService) ClearKey(key string) error {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	_, err := redis.Int(conn.Do("DEL", key))
 	return err
@@ -119,7 +158,10 @@ func (service *RedisService) ClearKey(key string) error {
 
 // ClearByKeyPrefix 清理指定前缀的KEY
 func (service *RedisService) ClearByKeyPrefix(keyPrefix string) (int, error) {
-	conn := service.RedisPool.Get()
+	if service.ClusterPool != nil {
+		return clusterClearByKeyPrefix(service.ClusterPool, keyPrefix)
+	}
+	conn := service.GetConn()
 	defer conn.Close()
 	keyPattner := fmt.Sprintf("%s*", keyPrefix)
 	//扫描Key
@@ -138,6 +180,30 @@ func (service *RedisService) ClearByKeyPrefix(keyPrefix string) (int, error) {
 	return 0, nil
 }
 
+// 集群模式下在每个主节点上扫描,Key可能不在同一个槽,逐个删除
+func clusterClearByKeyPrefix(clusterPool *RedisClusterPool, keyPrefix string) (int, error) {
+	keyPattner := fmt.Sprintf("%s*", keyPrefix)
+	count := 0
+	for _, addr := range clusterPool.Masters() {
+		nodeConn := clusterPool.GetByAddr(addr)
+		keys, err := scanKeysWithConn(nodeConn, 0, keyPattner, nil, 1000)
+		if nil != err {
+			nodeConn.Close()
+			return count, err
+		}
+		for _, key := range keys {
+			num, err := redis.Int(nodeConn.Do("DEL", key))
+			if nil != err {
+				nodeConn.Close()
+				return count, err
+			}
+			count += num
+		}
+		nodeConn.Close()
+	}
+	return count, nil
+}
+
 // ...
 func scanKeysWithConn(conn redis.Conn, cur int, keyPattner string, lastKeys []string, maxLen int) ([]string, error) {
 	reply, err := conn.Do("SCAN", cur, "MATCH", keyPattner, "COUNT", maxLen)
@@ -165,11 +231,9 @@ func scanKeysWithConn(conn redis.Conn, cur int, keyPattner string, lastKeys []st
 // expiry 过期时间-秒
 // tries 重试次数
 // delay 重试间隔时间
-func (service *RedisService) LockRun(key, value string, expiry int, tries, delay int, runFun func() any) (result any, err error) {
-	op1 := RedisMutexSetExpiry(time.Duration(expiry) * time.Second)
-	op2 := RedisMutexSetDelay(time.Duration(delay) * time.Millisecond)
-	op3 := RedisMutexSetTries(tries)
-	mutex := RedisNewMutex(key, value, service, op1, op2, op3)
+// options 其他配置,如 RedisMutexSetReentrant、RedisMutexSetWatchdog
+func (service *RedisService) LockRun(key, value string, expiry int, tries, delay int, runFun func() any, options ...RedisMutexOption) (result any, err error) {
+	mutex := service.NewMutex(key, value, expiry, tries, delay, options...)
 	if mutex.RedisLock() {
 		defer mutex.RedisReleseLock()
 		return runFun(), err
@@ -177,9 +241,22 @@ func (service *RedisService) LockRun(key, value string, expiry int, tries, delay
 	return nil, nerror.NewRunTimeError(fmt.Sprintf("[%v]-[%v]未获取到锁", key, value))
 }
 
+// 等待获取锁直到ctx被取消,获取后执行runFun
+// expiry 过期时间-秒
+// options 其他配置,如 RedisMutexSetDelay、RedisMutexSetReentrant、RedisMutexSetWatchdog
+func (service *RedisService) LockRunCtx(ctx context.Context, key, value string, expiry int, runFun func() any, options ...RedisMutexOption) (result any, err error) {
+	op1 := RedisMutexSetExpiry(time.Duration(expiry) * time.Second)
+	mutex := RedisNewMutex(key, value, service, append([]RedisMutexOption{op1}, options...)...)
+	if err := mutex.RedisLockCtx(ctx); err != nil {
+		return nil, err
+	}
+	defer mutex.RedisReleseLock()
+	return runFun(), nil
+}
+
 // 队列消息写入
 func (service *RedisService) Producer(queueKey string, message string) error {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	defer conn.Close()
 	replay, err := conn.Do("RPUSH", queueKey, message) // 或 "RPUSH"
 	slog.Debug(fmt.Sprintf("队列[%s]中目前有[%v]条数据,当前写入[%v]", queueKey, replay, message))
@@ -188,7 +265,7 @@ func (service *RedisService) Producer(queueKey string, message string) error {
 
 // 队列消息读取
 func (service *RedisService) Consumer(queueKey string, msgch chan string) {
-	conn := service.RedisPool.Get()
+	conn := service.GetConn()
 	for {
 		// BLPOP 返回格式: [队列名, 元素值]
 		reply, err := redis.Strings(conn.Do("BLPOP", queueKey, 0)) // 0 表示无限阻塞
@@ -198,7 +275,7 @@ func (service *RedisService) Consumer(queueKey string, msgch chan string) {
 			conn.Close()
 			time.Sleep(3 * time.Second)
 			//重新连接
-			conn = service.RedisPool.Get()
+			conn = service.GetConn()
 			continue
 		} else {
 			message := reply[1]
@@ -207,9 +284,19 @@ func (service *RedisService) Consumer(queueKey string, msgch chan string) {
 	}
 }
 
-func (service *RedisService) NewMutex(k, v string, expiry, tries, delay int) *RedisMutex {
+func (service *RedisService) NewMutex(k, v string, expiry, tries, delay int, options ...RedisMutexOption) *RedisMutex {
 	op1 := RedisMutexSetExpiry(time.Duration(expiry) * time.Second)
 	op2 := RedisMutexSetDelay(time.Duration(delay) * time.Millisecond)
 	op3 := RedisMutexSetTries(tries)
-	return RedisNewMutex(k, v, service, op1, op2, op3)
+	return RedisNewMutex(k, v, service, append([]RedisMutexOption{op1, op2, op3}, options...)...)
+}
+
+// 创建基于Redis Stream的可靠队列
+func (service *RedisService) NewStreamQueue(streamKey, groupName, consumerName string, options ...RedisStreamQueueOption) *RedisStreamQueue {
+	return RedisNewStreamQueue(streamKey, groupName, consumerName, service, options...)
+}
+
+// 创建基于Redis有序集合的延时队列
+func (service *RedisService) NewDelayQueue(queueKey string, options ...RedisDelayQueueOption) *RedisDelayQueue {
+	return RedisNewDelayQueue(queueKey, service, options...)
 }
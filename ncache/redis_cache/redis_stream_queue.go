@@ -0,0 +1,410 @@
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/niexqc/nlibs/nerror"
+)
+
+const (
+	streamFieldBody          = "body"
+	streamFieldSrcId         = "srcId"
+	streamFieldDeliveryCount = "deliveryCount"
+	streamFieldDeadTime      = "deadTime"
+)
+
+// 基于Redis Stream的可靠队列
+// 消息通过消费组读取,处理成功后XACK确认;处理失败或进程崩溃的消息保留在Pending列表中,
+// 空闲超过claimIdle后由其他消费者重新认领,超过最大投递次数后转入死信队列
+// 需要Redis 6.2及以上版本(XPENDING IDLE);Redis集群下streamKey与死信队列Key需使用相同的HashTag,如 {order}:stream
+type RedisStreamQueue struct {
+	redisService *RedisService
+	//Stream的Key
+	streamKey string
+	//消费组名称
+	groupName string
+	//消费者名称,同一消费组内唯一
+	consumerName string
+	//死信队列的Key,默认 streamKey+":dead"
+	deadLetterKey string
+	//最多投递次数,超过后转入死信队列
+	maxDelivery int64
+	//消息Pending超过多少时间后允许被重新认领
+	claimIdle time.Duration
+	//XREADGROUP每次阻塞等待的时间,也是检查ctx是否取消的间隔
+	blockTime time.Duration
+	//每次读取的消息数量
+	batchSize int
+	//Stream最大长度(近似裁剪),0表示不裁剪
+	maxLen int64
+	//确认后是否删除消息
+	delAfterAck bool
+}
+
+// 队列消息
+type RedisStreamMsg struct {
+	Id            string
+	Body          string
+	DeliveryCount int64
+}
+
+// Pending中的消息详情
+type RedisStreamPending struct {
+	Id            string
+	Consumer      string
+	Idle          time.Duration
+	DeliveryCount int64
+}
+
+// 创建可靠队列
+// 默认最多投递5次,Pending超过30秒可被重新认领,每次阻塞2秒,每次读取10条
+func RedisNewStreamQueue(streamKey, groupName, consumerName string, redisService *RedisService, options ...RedisStreamQueueOption) *RedisStreamQueue {
+	queue := &RedisStreamQueue{
+		redisService:  redisService,
+		streamKey:     streamKey,
+		groupName:     groupName,
+		consumerName:  consumerName,
+		deadLetterKey: streamKey + ":dead",
+		maxDelivery:   5,
+		claimIdle:     30 * time.Second,
+		blockTime:     2 * time.Second,
+		batchSize:     10,
+		delAfterAck:   true,
+	}
+	for _, option := range options {
+		option.Apply(queue)
+	}
+	return queue
+}
+
+// 配置可靠队列
+type RedisStreamQueueOption interface {
+	Apply(*RedisStreamQueue)
+}
+
+// 可靠队列的配置方法
+type StreamQueueOptionFunc func(*RedisStreamQueue)
+
+// Apply 实现RedisStreamQueueOption.Apply
+func (f StreamQueueOptionFunc) Apply(queue *RedisStreamQueue) {
+	f(queue)
+}
+
+// 设置死信队列的Key
+func RedisStreamQueueSetDeadLetterKey(deadLetterKey string) RedisStreamQueueOption {
+	return StreamQueueOptionFunc(func(q *RedisStreamQueue) {
+		q.deadLetterKey = deadLetterKey
+	})
+}
+
+// 设置最多投递次数
+func RedisStreamQueueSetMaxDelivery(maxDelivery int64) RedisStreamQueueOption {
+	return StreamQueueOptionFunc(func(q *RedisStreamQueue) {
+		q.maxDelivery = maxDelivery
+	})
+}
+
+// 设置Pending超过多少时间后允许被重新认领
+func RedisStreamQueueSetClaimIdle(claimIdle time.Duration) RedisStreamQueueOption {
+	return StreamQueueOptionFunc(func(q *RedisStreamQueue) {
+		q.claimIdle = claimIdle
+	})
+}
+
+// 设置每次阻塞读取的时间
+func RedisStreamQueueSetBlockTime(blockTime time.Duration) RedisStreamQueueOption {
+	return StreamQueueOptionFunc(func(q *RedisStreamQueue) {
+		q.blockTime = blockTime
+	})
+}
+
+// 设置每次读取的消息数量
+func RedisStreamQueueSetBatchSize(batchSize int) RedisStreamQueueOption {
+	return StreamQueueOptionFunc(func(q *RedisStreamQueue) {
+		q.batchSize = batchSize
+	})
+}
+
+// 设置Stream最大长度(近似裁剪)
+func RedisStreamQueueSetMaxLen(maxLen int64) RedisStreamQueueOption {
+	return StreamQueueOptionFunc(func(q *RedisStreamQueue) {
+		q.maxLen = maxLen
+	})
+}
+
+// 设置确认后是否删除消息
+func RedisStreamQueueSetDelAfterAck(delAfterAck bool) RedisStreamQueueOption {
+	return StreamQueueOptionFunc(func(q *RedisStreamQueue) {
+		q.delAfterAck = delAfterAck
+	})
+}
+
+// 创建消费组,Stream不存在时自动创建,消费组已存在时忽略
+func (q *RedisStreamQueue) InitGroup() error {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	_, err := conn.Do("XGROUP", "CREATE", q.streamKey, q.groupName, "0", "MKSTREAM")
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// 写入消息,返回消息ID
+func (q *RedisStreamQueue) Produce(message string) (string, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	args := redis.Args{q.streamKey}
+	if q.maxLen > 0 {
+		args = args.Add("MAXLEN", "~", q.maxLen)
+	}
+	args = args.Add("*", streamFieldBody, message)
+	return redis.String(conn.Do("XADD", args...))
+}
+
+// 消费消息,阻塞直到ctx被取消
+// handler返回nil时确认消息;返回错误或panic时消息保留在Pending中,等待重新投递
+func (q *RedisStreamQueue) Consume(ctx context.Context, handler func(msg *RedisStreamMsg) error) error {
+	if err := q.InitGroup(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug(fmt.Sprintf("队列[%s]消费者[%s]已停止", q.streamKey, q.consumerName))
+			return nil
+		default:
+		}
+		msgs, err := q.claimIdleMsgs()
+		if err == nil && len(msgs) == 0 {
+			msgs, err = q.readNewMsgs()
+		}
+		if err != nil {
+			slog.Warn(fmt.Sprintf("队列[%s]消费失败: %v, 3秒后重试中", q.streamKey, err))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(3 * time.Second):
+			}
+			continue
+		}
+		for _, msg := range msgs {
+			if herr := q.handleMsg(msg, handler); herr != nil {
+				slog.Warn(fmt.Sprintf("队列[%s]消息[%s]第%d次处理失败: %v", q.streamKey, msg.Id, msg.DeliveryCount, herr))
+				continue
+			}
+			if aerr := q.Ack(msg.Id); aerr != nil {
+				slog.Error(fmt.Sprintf("队列[%s]消息[%s]确认失败: %v", q.streamKey, msg.Id, aerr))
+			}
+		}
+	}
+}
+
+func (q *RedisStreamQueue) handleMsg(msg *RedisStreamMsg, handler func(msg *RedisStreamMsg) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = nerror.NewRunTimeErrorFmt("处理消息发生异常:%v", r)
+		}
+	}()
+	return handler(msg)
+}
+
+// 确认消息
+func (q *RedisStreamQueue) Ack(ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	return q.ackWithConn(conn, ids...)
+}
+
+func (q *RedisStreamQueue) ackWithConn(conn redis.Conn, ids ...string) error {
+	args := redis.Args{q.streamKey, q.groupName}.AddFlat(ids)
+	if _, err := conn.Do("XACK", args...); err != nil {
+		return err
+	}
+	if q.delAfterAck {
+		_, err := conn.Do("XDEL", redis.Args{q.streamKey}.AddFlat(ids)...)
+		return err
+	}
+	return nil
+}
+
+// 读取新消息
+func (q *RedisStreamQueue) readNewMsgs() ([]*RedisStreamMsg, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	// 阻塞读取的时间可能超过连接池配置的读超时
+	reply, err := redis.Values(redis.DoWithTimeout(conn, q.blockTime+5*time.Second, "XREADGROUP", "GROUP", q.groupName, q.consumerName,
+		"COUNT", q.batchSize, "BLOCK", q.blockTime.Milliseconds(), "STREAMS", q.streamKey, ">"))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]*RedisStreamMsg, 0)
+	for _, streamReply := range reply {
+		streamVals, err := redis.Values(streamReply, nil)
+		if err != nil || len(streamVals) != 2 {
+			return nil, nerror.NewRunTimeError("XREADGROUP返回格式错误")
+		}
+		entries, err := parseStreamEntries(streamVals[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range entries {
+			v.DeliveryCount = 1
+		}
+		msgs = append(msgs, entries...)
+	}
+	return msgs, nil
+}
+
+// 认领空闲超时的Pending消息,超过最大投递次数的转入死信队列
+func (q *RedisStreamQueue) claimIdleMsgs() ([]*RedisStreamMsg, error) {
+	pendings, err := q.pendingByIdle(q.claimIdle, q.batchSize)
+	if err != nil || len(pendings) == 0 {
+		return nil, err
+	}
+	claimIds := make([]string, 0, len(pendings))
+	deliveryCounts := make(map[string]int64, len(pendings))
+	for _, p := range pendings {
+		if p.DeliveryCount >= q.maxDelivery {
+			if err := q.moveToDeadLetter(p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		claimIds = append(claimIds, p.Id)
+		deliveryCounts[p.Id] = p.DeliveryCount
+	}
+	if len(claimIds) == 0 {
+		return nil, nil
+	}
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	args := redis.Args{q.streamKey, q.groupName, q.consumerName, q.claimIdle.Milliseconds()}.AddFlat(claimIds)
+	reply, err := conn.Do("XCLAIM", args...)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := parseStreamEntries(reply)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range msgs {
+		// XCLAIM 会使投递次数加1
+		v.DeliveryCount = deliveryCounts[v.Id] + 1
+	}
+	return msgs, nil
+}
+
+// 将消息写入死信队列并从Stream中确认,在同一脚本中先XACK,确认成功才写入死信,避免多个消费者重复转入
+func (q *RedisStreamQueue) moveToDeadLetter(p *RedisStreamPending) error {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	delAfterAck := 0
+	if q.delAfterAck {
+		delAfterAck = 1
+	}
+	moved, err := redis.Int(RdisScriptStreamDeadLetter.Do(conn, q.streamKey, q.deadLetterKey, q.groupName, p.Id,
+		p.DeliveryCount, time.Now().Format("2006-01-02 15:04:05"), delAfterAck))
+	if err != nil {
+		return err
+	}
+	if moved == 1 {
+		slog.Warn(fmt.Sprintf("队列[%s]消息[%s]已投递%d次,转入死信队列[%s]", q.streamKey, p.Id, p.DeliveryCount, q.deadLetterKey))
+	}
+	return nil
+}
+
+// Pending消息数量
+func (q *RedisStreamQueue) PendingCount() (int64, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	reply, err := redis.Values(conn.Do("XPENDING", q.streamKey, q.groupName))
+	if err != nil {
+		return 0, err
+	}
+	if len(reply) == 0 {
+		return 0, nil
+	}
+	return redis.Int64(reply[0], nil)
+}
+
+// 查看Pending中的消息,最多返回count条
+func (q *RedisStreamQueue) Pending(count int) ([]*RedisStreamPending, error) {
+	return q.pendingByIdle(0, count)
+}
+
+func (q *RedisStreamQueue) pendingByIdle(minIdle time.Duration, count int) ([]*RedisStreamPending, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	args := redis.Args{q.streamKey, q.groupName}
+	if minIdle > 0 {
+		args = args.Add("IDLE", minIdle.Milliseconds())
+	}
+	args = args.Add("-", "+", count)
+	reply, err := redis.Values(conn.Do("XPENDING", args...))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*RedisStreamPending, 0, len(reply))
+	for _, item := range reply {
+		vals, err := redis.Values(item, nil)
+		if err != nil || len(vals) != 4 {
+			return nil, nerror.NewRunTimeError("XPENDING返回格式错误")
+		}
+		id, _ := redis.String(vals[0], nil)
+		consumer, _ := redis.String(vals[1], nil)
+		idle, _ := redis.Int64(vals[2], nil)
+		deliveryCount, _ := redis.Int64(vals[3], nil)
+		result = append(result, &RedisStreamPending{Id: id, Consumer: consumer, Idle: time.Duration(idle) * time.Millisecond, DeliveryCount: deliveryCount})
+	}
+	return result, nil
+}
+
+// 查看死信队列中的消息,最多返回count条
+func (q *RedisStreamQueue) DeadLetters(count int) ([]*RedisStreamMsg, error) {
+	conn := q.redisService.GetConn()
+	defer conn.Close()
+	reply, err := conn.Do("XRANGE", q.deadLetterKey, "-", "+", "COUNT", count)
+	if err != nil {
+		return nil, err
+	}
+	return parseStreamEntries(reply)
+}
+
+// 解析 [[id, [field, value, ...]], ...] 格式的返回
+func parseStreamEntries(reply any) ([]*RedisStreamMsg, error) {
+	entries, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]*RedisStreamMsg, 0, len(entries))
+	for _, entry := range entries {
+		vals, err := redis.Values(entry, nil)
+		// 已被删除的消息XCLAIM时字段为nil
+		if err != nil || len(vals) != 2 || vals[1] == nil {
+			continue
+		}
+		id, _ := redis.String(vals[0], nil)
+		fields, err := redis.StringMap(vals[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		msg := &RedisStreamMsg{Id: id, Body: fields[streamFieldBody]}
+		if v, ok := fields[streamFieldDeliveryCount]; ok {
+			msg.DeliveryCount, _ = redis.Int64([]byte(v), nil)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
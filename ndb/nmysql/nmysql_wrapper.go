@@ -78,6 +78,11 @@ func (ndbw *NMysqlWrapper) CloseSqlxDb() error {
 	return nerr
 }
 
+// 检查数据库连接,可用于就绪检查
+func (ndbw *NMysqlWrapper) Ping(ctx context.Context) error {
+	return ndbw.sqlxDb.PingContext(ctx)
+}
+
 //	 查询单个字段单个值
 //		 sqlStr:=select id from table where id=?
 //		 str:=ndb.SelectOne[string](ndbw,sql,id)
@@ -83,6 +83,11 @@ func SelectList[T any](ndbw *NPgWrapper, sqlStr string, args ...any) (tlist []*T
 	return *objs, err
 }
 
+// 检查数据库连接,可用于就绪检查
+func (ndbw *NPgWrapper) Ping(ctx context.Context) error {
+	return ndbw.sqlxDb.PingContext(ctx)
+}
+
 // SqlLimitStr
 // pageNo 页码从1开始
 func (ndbw *NPgWrapper) SqlLimitStr(pageNo, pageSize int) string {
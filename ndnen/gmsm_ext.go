@@ -2,6 +2,7 @@ package ndnen
 
 import (
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"encoding/asn1"
 	"encoding/base64"
@@ -176,6 +177,10 @@ func Sm2EncryptToHex(pubKey *sm2.PublicKey, plaintext string) string {
 
 // SM2 私钥解密
 func Sm2Decrypt(privKey *sm2.PrivateKey, ciphertext []byte) (bool, []byte) {
+	// 04 + C1(64) + C3(32),长度不足时gmsm会越界
+	if len(ciphertext) < 97 {
+		return false, nil
+	}
 	plaintext, err := sm2.Decrypt(privKey, ciphertext, sm2.C1C3C2)
 	if err != nil {
 		return false, nil
@@ -212,7 +217,9 @@ func Sm2SignByPriKey(privKey *sm2.PrivateKey, srcStr string) string {
 func Sm2VerifyByPubKey(pubKey *sm2.PublicKey, srcStr, b64DerSign string) bool {
 	var sm2Sign sm2Signature
 	derBytes, _ := base64.StdEncoding.DecodeString(b64DerSign)
-	asn1.Unmarshal(derBytes, &sm2Sign)
+	if _, err := asn1.Unmarshal(derBytes, &sm2Sign); err != nil || sm2Sign.R == nil || sm2Sign.S == nil {
+		return false
+	}
 	return sm2.Sm2Verify(pubKey, []byte(srcStr), signUserId, sm2Sign.R, sm2Sign.S)
 }
 
@@ -220,7 +227,9 @@ func Sm2VerifyByPubKey(pubKey *sm2.PublicKey, srcStr, b64DerSign string) bool {
 func Sm2VerifyB64SrcByPubKey(pubKey *sm2.PublicKey, b64SrcStr, b64DerSign string) bool {
 	var sm2Sign sm2Signature
 	derBytes, _ := base64.StdEncoding.DecodeString(b64DerSign)
-	asn1.Unmarshal(derBytes, &sm2Sign)
+	if _, err := asn1.Unmarshal(derBytes, &sm2Sign); err != nil || sm2Sign.R == nil || sm2Sign.S == nil {
+		return false
+	}
 	strBytes, _ := base64.StdEncoding.DecodeString(b64SrcStr)
 	return sm2.Sm2Verify(pubKey, strBytes, signUserId, sm2Sign.R, sm2Sign.S)
 }
@@ -362,6 +371,27 @@ func Sm3hash(data []byte) []byte {
 	return h.Sum(nil)
 }
 
+// HMAC-SM3
+func HmacSm3(key, data []byte) []byte {
+	h := hmac.New(sm3.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// HMAC-SM3,返回小写Hex
+func HmacSm3Hex(key, srcStr string) string {
+	return hex.EncodeToString(HmacSm3([]byte(key), []byte(srcStr)))
+}
+
+// 校验HMAC-SM3的Hex签名,忽略大小写,比较耗时与内容无关
+func HmacSm3VerifyHex(key, srcStr, hexSign string) bool {
+	sign, err := hex.DecodeString(hexSign)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(HmacSm3([]byte(key), []byte(srcStr)), sign)
+}
+
 func Sm4EcbPkcs5EnData2HexStr(hexKey, plaintext string) (string, error) {
 	hexKeyData, _ := hex.DecodeString(hexKey)
 	// 检查密钥长度
@@ -0,0 +1,218 @@
+package ngin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+// 默认脱敏的字段名
+var DefaultLogMaskKeys = []string{"password", "passwd", "pwd", "idCard", "phone", "mobile"}
+
+// 超过该大小的请求体/响应体不再按JSON解析,只按字段名脱敏
+const logBodyParseLimit = 1 << 20
+
+// 请求体与响应体的日志脱敏
+type NLogMasker struct {
+	keys  map[string]bool
+	paths [][]string
+	// 解析失败时按字段名脱敏 "key":"value"
+	keyReg *regexp.Regexp
+}
+
+// 创建脱敏
+// keys 字段名,不区分大小写
+// paths JSON路径,以.分隔,数组自动展开,*匹配任意字段
+func NewNLogMasker(keys, paths []string) *NLogMasker {
+	masker := &NLogMasker{keys: map[string]bool{}}
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		masker.keys[strings.ToLower(key)] = true
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+	for _, p := range paths {
+		masker.paths = append(masker.paths, strings.Split(p, "."))
+	}
+	if len(quoted) > 0 {
+		masker.keyReg = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|[-0-9.]+)`)
+	}
+	return masker
+}
+
+// 脱敏后的内容,JSON解析失败时按字段名替换
+func (m *NLogMasker) Mask(body []byte) string {
+	if len(body) <= logBodyParseLimit {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var data any
+		if err := decoder.Decode(&data); err == nil && !decoder.More() {
+			data = m.maskValue(data, nil)
+			if masked, err := json.Marshal(data); err == nil {
+				return string(masked)
+			}
+		}
+	}
+	text := string(body)
+	if m.keyReg != nil {
+		text = m.keyReg.ReplaceAllString(text, `$1"****"`)
+	}
+	return text
+}
+
+// path为从根到当前值的字段名
+func (m *NLogMasker) maskValue(val any, path []string) any {
+	switch v := val.(type) {
+	case map[string]any:
+		for key, item := range v {
+			itemPath := append(path[:len(path):len(path)], key)
+			if m.keys[strings.ToLower(key)] || m.matchPath(itemPath) {
+				v[key] = maskLogValue(item)
+			} else {
+				v[key] = m.maskValue(item, itemPath)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = m.maskValue(item, path)
+		}
+	}
+	return val
+}
+
+func (m *NLogMasker) matchPath(path []string) bool {
+	for _, p := range m.paths {
+		if len(p) != len(path) {
+			continue
+		}
+		matched := true
+		for i := range p {
+			if p[i] != "*" && p[i] != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// 字符串保留首尾各1/4(最多4个字符),其他类型替换为****
+func maskLogValue(val any) any {
+	str, ok := val.(string)
+	if !ok {
+		return "****"
+	}
+	runes := []rune(str)
+	keep := min(len(runes)/4, 4)
+	return string(runes[:keep]) + "****" + string(runes[len(runes)-keep:])
+}
+
+// 访问日志,请求结束后打印一条结构化日志
+// 字段:method,route,path,query,status,latency_ms,bytes,client_ip,trace_id,visit_src,user_agent,req_body,resp_body
+// 在CompressHandlerFunc、EndnHandlerFunc之前添加时记录的是压缩或加密后的响应,此时不记录resp_body
+func LoggerHandlerFuncWithConf(conf *nyaml.YamlConfAccessLog) gin.HandlerFunc {
+	slog.Debug("Add Middleware LoggerHandlerFunc")
+	maxBodySize := conf.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = 2048
+	}
+	masker := NewNLogMasker(conf.MaskKeys, conf.MaskPaths)
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		var reqBody []byte
+		if conf.ShowReqBody && logBodyContentType(ctx.GetHeader("Content-Type")) {
+			if headerVo := GetHeaderVoFromCtx(ctx); strings.Contains(headerVo.ContentType, "application/json") {
+				reqBody = headerVo.ReqBody
+			} else {
+				reqBody = readAndResetBody(ctx)
+			}
+		}
+		var respWriter *accessLogWriter
+		if conf.ShowRespBody {
+			respWriter = &accessLogWriter{ResponseWriter: ctx.Writer}
+			ctx.Writer = respWriter
+			defer func() {
+				ctx.Writer = respWriter.ResponseWriter
+			}()
+		}
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		attrs := []any{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", route),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("query", ctx.Request.URL.RawQuery),
+			slog.Int("status", ctx.Writer.Status()),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+			slog.Int("bytes", max(ctx.Writer.Size(), 0)),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.String("trace_id", ntools.SlogGetTraceId()),
+			slog.String("visit_src", ctx.GetHeader("Visit-Src")),
+			slog.String("user_agent", (&ntools.NString{S: ctx.Request.UserAgent()}).CutString(120)),
+		}
+		if conf.ShowReqBody {
+			attrs = append(attrs, slog.String("req_body", logBodyStr(masker, reqBody, maxBodySize)))
+		}
+		if respWriter != nil {
+			respBody := []byte(nil)
+			header := respWriter.Header()
+			if logBodyContentType(header.Get("Content-Type")) && header.Get("Content-Encoding") == "" && header.Get(EndnHeaderResp) == "" {
+				respBody = respWriter.buf.Bytes()
+			}
+			attrs = append(attrs, slog.String("resp_body", logBodyStr(masker, respBody, maxBodySize)))
+		}
+		level := slog.LevelInfo
+		if ctx.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		slog.Log(ctx.Request.Context(), level, "Access", attrs...)
+	}
+}
+
+func logBodyContentType(contentType string) bool {
+	return strings.Contains(contentType, "json") || strings.Contains(contentType, "text") || strings.Contains(contentType, "xml")
+}
+
+// 脱敏后截断,换行替换为\n保持一行
+func logBodyStr(masker *NLogMasker, body []byte, maxBodySize int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	text := masker.Mask(body)
+	if len(text) > maxBodySize {
+		text = fmt.Sprintf("%s...(%d bytes)", strings.ToValidUTF8(text[:maxBodySize], ""), len(body))
+	}
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(text)
+}
+
+// 写出响应的同时保留一份,超过logBodyParseLimit后不再保留
+type accessLogWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *accessLogWriter) Write(data []byte) (int, error) {
+	if w.buf.Len() < logBodyParseLimit {
+		w.buf.Write(data[:min(len(data), logBodyParseLimit-w.buf.Len())])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *accessLogWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,201 @@
+package ngin
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+// 就绪检查,返回错误表示未就绪
+// 如 NMysqlWrapper.Ping、func(ctx) error { return redisService.Ping() }、func(ctx) error { return producer.Ready() }
+type NReadyCheck func(ctx context.Context) error
+
+// 单项就绪检查的结果
+type NReadyResult struct {
+	Name      string `json:"name" zhdesc:"检查项"`
+	Ok        bool   `json:"ok" zhdesc:"是否就绪"`
+	Err       string `json:"err" zhdesc:"错误信息"`
+	LatencyMs int64  `json:"latencyMs" zhdesc:"耗时-毫秒"`
+}
+
+// 路由信息
+type NGinRouteInfo struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+// 管理接口
+type NGinAdmin struct {
+	nGin         *NGin
+	readyTimeout time.Duration
+	mu           sync.RWMutex
+	checkNames   []string
+	checks       map[string]NReadyCheck
+}
+
+// 在group下注册管理接口
+// GET /health 存活检查; GET /ready 执行全部就绪检查,未就绪时返回503
+// GET /routes 路由列表; /debug/pprof/ 运行时性能分析
+// IP在AllowCidrs中或携带正确的Token时允许访问,两者都未配置时panic
+func (nGin *NGin) RegisterAdmin(group *NGinGroup, conf *nyaml.YamlConfAdmin) *NGinAdmin {
+	readyTimeout := time.Duration(conf.ReadyTimeout) * time.Second
+	if readyTimeout <= 0 {
+		readyTimeout = 3 * time.Second
+	}
+	admin := &NGinAdmin{nGin: nGin, readyTimeout: readyTimeout, checks: map[string]NReadyCheck{}}
+	adminGroup := group.Group("", adminGuardHandlerFunc(conf))
+
+	adminGroup.GET("/health", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, NewOkBaseResp(map[string]string{"status": "UP"}))
+	})
+	adminGroup.GET("/ready", admin.readyHandler)
+	adminGroup.GET("/routes", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, NewOkBaseResp(nGin.RouteInfos()))
+	})
+
+	adminGroup.GET("/debug/pprof/", gin.WrapF(pprof.Index))
+	adminGroup.GET("/debug/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	adminGroup.GET("/debug/pprof/profile", gin.WrapF(pprof.Profile))
+	adminGroup.GET("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	adminGroup.POST("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	adminGroup.GET("/debug/pprof/trace", gin.WrapF(pprof.Trace))
+	// pprof.Index按/debug/pprof/前缀识别profile名称,分组下需逐个注册
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		adminGroup.GET("/debug/pprof/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+	return admin
+}
+
+// 添加就绪检查,同名时覆盖
+func (admin *NGinAdmin) AddReadyCheck(name string, check NReadyCheck) {
+	admin.mu.Lock()
+	defer admin.mu.Unlock()
+	if _, ok := admin.checks[name]; !ok {
+		admin.checkNames = append(admin.checkNames, name)
+	}
+	admin.checks[name] = check
+}
+
+// 并发执行全部就绪检查,单项超过readyTimeout视为未就绪
+func (admin *NGinAdmin) Ready(ctx context.Context) ([]NReadyResult, bool) {
+	admin.mu.RLock()
+	names := append([]string{}, admin.checkNames...)
+	checks := make([]NReadyCheck, len(names))
+	for i, name := range names {
+		checks[i] = admin.checks[name]
+	}
+	admin.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, admin.readyTimeout)
+	defer cancel()
+	results := make([]NReadyResult, len(names))
+	wg := sync.WaitGroup{}
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runReadyCheck(ctx, names[i], checks[i])
+		}(i)
+	}
+	wg.Wait()
+	allOk := true
+	for _, result := range results {
+		allOk = allOk && result.Ok
+	}
+	return results, allOk
+}
+
+// 检查本身不响应ctx时,超时后不再等待
+func runReadyCheck(ctx context.Context, name string, check NReadyCheck) (result NReadyResult) {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- recoverValue2Err(r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result = NReadyResult{Name: name, Ok: err == nil, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Err = err.Error()
+		slog.Warn(fmt.Sprintf("就绪检查[%s]失败:%v", name, err))
+	}
+	return result
+}
+
+func (admin *NGinAdmin) readyHandler(ctx *gin.Context) {
+	results, ok := admin.Ready(ctx.Request.Context())
+	if ok {
+		ctx.JSON(http.StatusOK, NewOkBaseResp(results))
+		return
+	}
+	resp := NewNoBaseResp(RespCode_RunTime_Err, "服务未就绪")
+	resp.Data = results
+	ctx.JSON(http.StatusServiceUnavailable, resp)
+}
+
+// 已注册的路由,按路径与方法排序
+func (nGin *NGin) RouteInfos() []NGinRouteInfo {
+	routes := nGin.GinEngine.Routes()
+	infos := make([]NGinRouteInfo, 0, len(routes))
+	for _, v := range routes {
+		infos = append(infos, NGinRouteInfo{Method: v.Method, Path: v.Path, Handler: v.Handler})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Path != infos[j].Path {
+			return infos[i].Path < infos[j].Path
+		}
+		return infos[i].Method < infos[j].Method
+	})
+	return infos
+}
+
+// IP在允许列表中或Token正确时允许访问,否则返回RespCode_Forbidden
+// 仅信任来自TrustedProxies的X-Forwarded-For/X-Real-Ip,未配置时使用连接的IP
+func adminGuardHandlerFunc(conf *nyaml.YamlConfAdmin) gin.HandlerFunc {
+	if len(conf.AllowCidrs) == 0 && conf.Token == "" {
+		panic(nerror.NewRunTimeError("管理接口需配置allowCidrs或token"))
+	}
+	allows := parseIpPrefixes(conf.AllowCidrs)
+	proxies := parseIpPrefixes(conf.TrustedProxies)
+	return func(ctx *gin.Context) {
+		clientIp := clientIpByTrustedProxies(ctx.Request, proxies)
+		if addr, err := netip.ParseAddr(clientIp); err == nil && ipInPrefixes(addr, allows) {
+			ctx.Next()
+			return
+		}
+		if conf.Token != "" {
+			token := ctx.GetHeader("Admin-Token")
+			if auth := ctx.GetHeader("Authorization"); token == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+				token = strings.TrimSpace(auth[7:])
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(conf.Token)) == 1 {
+				ctx.Next()
+				return
+			}
+		}
+		slog.Warn(fmt.Sprintf("IP[%s]访问管理接口被拒绝:%s", clientIp, ctx.Request.URL.Path))
+		ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Forbidden, "无访问权限"))
+	}
+}
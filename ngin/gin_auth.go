@@ -0,0 +1,206 @@
+package ngin
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/njson"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+const nGinPrincipalKey = "NGinPrincipal"
+
+// 登录用户
+type NGinPrincipal struct {
+	UserId   string         `json:"userId"`
+	UserName string         `json:"userName"`
+	Roles    []string       `json:"roles"`
+	Perms    []string       `json:"perms"`
+	Extra    map[string]any `json:"extra"`
+	// Token过期时间
+	ExpiresAt time.Time `json:"expiresAt"`
+	// 本次请求的Token
+	Token string `json:"-"`
+}
+
+// 是否拥有任一角色
+func (p *NGinPrincipal) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否拥有全部权限
+func (p *NGinPrincipal) HasAllPerms(perms ...string) bool {
+	for _, perm := range perms {
+		if !slices.Contains(p.Perms, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// Token验证,NJwt 与 CacheTokenVerifier 均已实现
+type NTokenVerifier interface {
+	Verify(token string) (*NGinPrincipal, error)
+}
+
+// 缓存Token的存储,RedisService 与 MemCacheService 均已实现
+type NTokenCache interface {
+	GetStr(key string) (string, error)
+	PutExStr(key string, val string, sencond int) error
+	KeySetExpire(key string, sencond int) error
+	ClearKey(key string) error
+}
+
+// 不透明Token,登录信息保存在缓存中
+type CacheTokenVerifier struct {
+	cache     NTokenCache
+	keyPrefix string
+	//过期时间-秒
+	expire int
+	//每次验证通过后是否重新计算过期时间
+	sliding bool
+}
+
+// 创建缓存Token
+// expire 过期时间-秒
+// sliding 为true时每次验证通过后延长过期时间
+func NewCacheTokenVerifier(cache NTokenCache, keyPrefix string, expire int, sliding bool) *CacheTokenVerifier {
+	return &CacheTokenVerifier{cache: cache, keyPrefix: keyPrefix, expire: expire, sliding: sliding}
+}
+
+// 登录后签发Token
+func (v *CacheTokenVerifier) Issue(principal *NGinPrincipal) (string, error) {
+	token := ntools.UUIDStr(false)
+	stored := *principal
+	stored.ExpiresAt = time.Now().Add(time.Duration(v.expire) * time.Second)
+	jsonStr, err := njson.Obj2JsonStr(stored)
+	if err != nil {
+		return "", err
+	}
+	if err := v.cache.PutExStr(v.keyPrefix+token, jsonStr, v.expire); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (v *CacheTokenVerifier) Verify(token string) (*NGinPrincipal, error) {
+	jsonStr, err := v.cache.GetStr(v.keyPrefix + token)
+	if err != nil || jsonStr == "" {
+		return nil, nerror.NewRunTimeError("Token不存在或已过期")
+	}
+	principal, err := njson.Str2Obj[NGinPrincipal](jsonStr)
+	if err != nil {
+		return nil, nerror.NewRunTimeErrorWithError("Token内容错误", err)
+	}
+	if v.sliding {
+		if err := v.cache.KeySetExpire(v.keyPrefix+token, v.expire); err == nil {
+			principal.ExpiresAt = time.Now().Add(time.Duration(v.expire) * time.Second)
+		}
+	}
+	return principal, nil
+}
+
+// 退出登录
+func (v *CacheTokenVerifier) Revoke(token string) error {
+	return v.cache.ClearKey(v.keyPrefix + token)
+}
+
+// 读取请求的Token,优先User-Token,其次Authorization: Bearer
+func ReadUserToken(ctx *gin.Context) string {
+	if token := ctx.GetHeader("User-Token"); token != "" {
+		return token
+	}
+	auth := ctx.GetHeader("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// 登录验证,验证通过后将NGinPrincipal写入上下文
+// 未携带Token或验证失败返回RespCode_UnLogin
+func AuthHandlerFunc(verifier NTokenVerifier) gin.HandlerFunc {
+	slog.Debug("Add Middleware AuthHandlerFunc")
+	return authHandlerFunc(verifier, ReadUserToken)
+}
+
+// 登录验证,请求头中没有Token时读取Query参数queryKey
+// 浏览器的EventSource与WebSocket无法设置请求头,SSE与WebSocket的升级请求使用
+func AuthHandlerFuncWithQuery(verifier NTokenVerifier, queryKey string) gin.HandlerFunc {
+	slog.Debug("Add Middleware AuthHandlerFuncWithQuery")
+	return authHandlerFunc(verifier, func(ctx *gin.Context) string {
+		if token := ReadUserToken(ctx); token != "" {
+			return token
+		}
+		return ctx.Query(queryKey)
+	})
+}
+
+func authHandlerFunc(verifier NTokenVerifier, readToken func(ctx *gin.Context) string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := readToken(ctx)
+		if token == "" {
+			ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_UnLogin, "未登录"))
+			return
+		}
+		principal, err := verifier.Verify(token)
+		if err != nil {
+			slog.Debug("Token验证失败:" + err.Error())
+			ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_UnLogin, "登录已过期,请重新登录"))
+			return
+		}
+		principal.Token = token
+		ctx.Set(nGinPrincipalKey, principal)
+		ctx.Next()
+	}
+}
+
+// 读取当前登录用户
+func GetPrincipal(ctx *gin.Context) (*NGinPrincipal, bool) {
+	if v, exist := ctx.Get(nGinPrincipalKey); exist {
+		if principal, ok := v.(*NGinPrincipal); ok {
+			return principal, true
+		}
+	}
+	return nil, false
+}
+
+// 要求拥有任一角色,需在AuthHandlerFunc之后使用
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return requirePrincipal(func(p *NGinPrincipal) bool {
+		return p.HasAnyRole(roles...)
+	})
+}
+
+// 要求拥有全部权限,需在AuthHandlerFunc之后使用
+func RequirePerms(perms ...string) gin.HandlerFunc {
+	return requirePrincipal(func(p *NGinPrincipal) bool {
+		return p.HasAllPerms(perms...)
+	})
+}
+
+// 未登录返回RespCode_UnLogin,无权限返回RespCode_Forbidden
+func requirePrincipal(allow func(p *NGinPrincipal) bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := GetPrincipal(ctx)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_UnLogin, "未登录"))
+			return
+		}
+		if !allow(principal) {
+			ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Forbidden, "无访问权限"))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,212 @@
+package ngin
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// 默认压缩的响应类型
+var DefaultCompressContentTypes = []string{
+	"application/json", "application/javascript", "application/xml", "image/svg+xml",
+	"text/html", "text/plain", "text/css", "text/javascript", "text/xml", "text/csv",
+}
+
+// 服务端支持的压缩方式,按优先级排列
+var compressEncodings = []string{"br", "gzip", "deflate"}
+
+var gzipWriterPool = sync.Pool{New: func() any {
+	w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+	return w
+}}
+
+// 响应压缩,支持br/gzip/deflate,按Accept-Encoding选择
+// minSize 响应体小于minSize字节时不压缩
+// contentTypes 需要压缩的响应类型,为空时使用DefaultCompressContentTypes
+// 已设置Content-Encoding、206分段响应及Range请求不压缩
+// 需在ETagHandlerFunc之前注册,ETag按未压缩的内容计算
+func CompressHandlerFunc(minSize int, contentTypes ...string) gin.HandlerFunc {
+	slog.Debug("Add Middleware CompressHandlerFunc")
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultCompressContentTypes
+	}
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodHead || ctx.GetHeader("Range") != "" {
+			ctx.Next()
+			return
+		}
+		encoding := negotiateEncoding(ctx.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			ctx.Next()
+			return
+		}
+		writer := &compressWriter{ResponseWriter: ctx.Writer, encoding: encoding, minSize: minSize, contentTypes: contentTypes}
+		ctx.Writer = writer
+		defer func() {
+			writer.finish()
+			ctx.Writer = writer.ResponseWriter
+		}()
+		ctx.Next()
+	}
+}
+
+// 按Accept-Encoding选择压缩方式,q=0表示不接受
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if val, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(val, 64); err == nil {
+				q = v
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range compressEncodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// 先缓存minSize字节再决定是否压缩,超过后以流的方式压缩写出
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding     string
+	minSize      int
+	contentTypes []string
+	buf          bytes.Buffer
+	compressor   io.WriteCloser
+	passthrough  bool
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	w.buf.Write(data)
+	if w.buf.Len() >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// 未决定是否压缩前不提前写出响应头
+func (w *compressWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// 流式响应(SSE等)刷新时不再等待minSize
+func (w *compressWriter) Flush() {
+	if w.compressor == nil && !w.passthrough {
+		w.decide(w.buf.Len() >= w.minSize)
+	}
+	if f, ok := w.compressor.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.passthrough = true
+	return w.ResponseWriter.Hijack()
+}
+
+// 决定是否压缩并写出已缓存的内容
+func (w *compressWriter) decide(sizeOk bool) error {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+	status := w.Status()
+	if !sizeOk || header.Get("Content-Encoding") != "" || status == http.StatusPartialContent || status == http.StatusNoContent ||
+		status == http.StatusNotModified || !contentTypeAllowed(header.Get("Content-Type"), w.contentTypes) {
+		w.passthrough = true
+	} else {
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		w.compressor = newCompressor(w.encoding, w.ResponseWriter)
+	}
+	data := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	if len(data) == 0 {
+		return nil
+	}
+	if w.compressor != nil {
+		_, err := w.compressor.Write(data)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(data)
+	return err
+}
+
+func (w *compressWriter) finish() {
+	if w.compressor == nil && !w.passthrough && w.buf.Len() > 0 {
+		w.decide(false)
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+		if gz, ok := w.compressor.(*gzip.Writer); ok {
+			gzipWriterPool.Put(gz)
+		}
+	}
+}
+
+func newCompressor(encoding string, writer io.Writer) io.WriteCloser {
+	switch encoding {
+	case "br":
+		return brotli.NewWriterLevel(writer, brotli.DefaultCompression)
+	case "deflate":
+		fw, _ := flate.NewWriter(writer, flate.DefaultCompression)
+		return fw
+	default:
+		gz := gzipWriterPool.Get().(*gzip.Writer)
+		gz.Reset(writer)
+		return gz
+	}
+}
+
+func contentTypeAllowed(contentType string, contentTypes []string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, t := range contentTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package ngin
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ndnen"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/nyaml"
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+// 请求头: SM2加密的会话密钥(Hex),明文为SM4的hexKey+hexIv共64位
+const EndnHeaderKey = "Endn-Key"
+
+// 响应头: 值为1时表示响应体为SM4加密后的Base64
+const EndnHeaderResp = "Endn-Resp"
+
+// 响应头: 响应体SM4加密使用的IV(Hex),每次响应随机生成
+const EndnHeaderIv = "Endn-Iv"
+
+// 报文加解密
+// 请求头Endn-Key携带SM2加密的会话密钥,请求体为SM4-CBC加密后的Base64,解密后以application/json交给后续处理
+// 响应体使用同一会话密钥与随机生成的IV进行SM4-CBC加密为Base64,响应头Endn-Resp为1,Endn-Iv为本次响应的IV
+// required 为true时拒绝未加密的请求,否则未携带Endn-Key的请求按明文处理
+// 响应会先缓存再加密,不适用于SSE、文件下载等流式响应
+func EndnHandlerFunc(conf *nyaml.YamlConfEndnKey, required bool) gin.HandlerFunc {
+	slog.Debug("Add Middleware EndnHandlerFunc")
+	priKey, err := ndnen.Sm2LoadPriKeyFromHex(conf.Sm2HexPriKey)
+	if err != nil {
+		panic(nerror.NewRunTimeErrorWithError("加载服务端SM2私钥失败", err))
+	}
+
+	endnFail := func(ctx *gin.Context, msg string) {
+		slog.Warn(fmt.Sprintf("%s\t%s\t报文解密失败:%s", ctx.Request.RequestURI, ctx.ClientIP(), msg))
+		ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Endn_Err, msg))
+	}
+
+	return func(ctx *gin.Context) {
+		endnKey := ctx.GetHeader(EndnHeaderKey)
+		if endnKey == "" {
+			if required {
+				endnFail(ctx, "请求未加密")
+				return
+			}
+			ctx.Next()
+			return
+		}
+		hexKey, hexIv, err := endnSessionKeyDecrypt(priKey, endnKey)
+		if err != nil {
+			endnFail(ctx, err.Error())
+			return
+		}
+		encBody, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			endnFail(ctx, "读取请求体失败")
+			return
+		}
+		if len(bytes.TrimSpace(encBody)) > 0 {
+			body, err := EndnDecryptBody(hexKey, hexIv, encBody)
+			if err != nil {
+				endnFail(ctx, err.Error())
+				return
+			}
+			// 密钥错误时填充仍可能恰好合法,按JSON再校验一次
+			if !json.Valid(body) {
+				endnFail(ctx, "解密后不是JSON数据")
+				return
+			}
+			endnResetReqBody(ctx, body)
+		}
+
+		writer := &endnRespWriter{ResponseWriter: ctx.Writer, buf: &bytes.Buffer{}}
+		ctx.Writer = writer
+		defer func() {
+			ctx.Writer = writer.ResponseWriter
+			writer.flushEncrypted(hexKey)
+		}()
+		ctx.Next()
+	}
+}
+
+// 客户端使用: 服务端SM2公钥加密会话密钥,返回Endn-Key的值
+func EndnSessionKeyEncrypt(pubKey *sm2.PublicKey, hexKey, hexIv string) string {
+	return ndnen.Sm2EncryptToHex(pubKey, hexKey+hexIv)
+}
+
+// SM4-CBC加密为Base64
+func EndnEncryptBody(hexKey, hexIv string, body []byte) ([]byte, error) {
+	encData, err := ndnen.Sm4CbcEnBytesByHexKey(hexKey, hexIv, body)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(encData)), nil
+}
+
+// 解密SM4-CBC加密的Base64
+func EndnDecryptBody(hexKey, hexIv string, encBody []byte) ([]byte, error) {
+	encData, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encBody)))
+	if err != nil {
+		return nil, nerror.NewRunTimeError("密文不是Base64数据")
+	}
+	body, err := ndnen.Sm4CbcDnBytesByHexKey(hexKey, hexIv, encData)
+	if err != nil {
+		return nil, nerror.NewRunTimeErrorWithError("SM4解密失败", err)
+	}
+	return body, nil
+}
+
+func endnSessionKeyDecrypt(priKey *sm2.PrivateKey, endnKey string) (hexKey, hexIv string, err error) {
+	ok, plaintext, err := ndnen.Sm2DecryptHex(priKey, endnKey)
+	if err == nil && !ok && !strings.HasPrefix(endnKey, "04") {
+		// 部分前端库的密文省略了04前缀
+		ok, plaintext, err = ndnen.Sm2DecryptHex(priKey, "04"+endnKey)
+	}
+	if err != nil || !ok {
+		return "", "", nerror.NewRunTimeError("会话密钥解密失败")
+	}
+	if len(plaintext) != 64 {
+		return "", "", nerror.NewRunTimeError("会话密钥长度错误")
+	}
+	if _, err := hex.DecodeString(plaintext); err != nil {
+		return "", "", nerror.NewRunTimeError("会话密钥不是Hex数据")
+	}
+	return plaintext[:32], plaintext[32:], nil
+}
+
+// 替换为解密后的请求体,HeaderSetHandlerFunc在之前执行时同步更新ReqBody
+func endnResetReqBody(ctx *gin.Context, body []byte) {
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	ctx.Request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	ctx.Request.ContentLength = int64(len(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	headerVo := GetHeaderVoFromCtx(ctx)
+	headerVo.ContentType = "application/json"
+	headerVo.ContentLength = int64(len(body))
+	headerVo.ReqBody = body
+}
+
+// 随机生成16字节的IV
+func endnGenHexIv() (string, error) {
+	iv := make([]byte, 16)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(iv), nil
+}
+
+// 缓存响应体,处理完成后加密写出
+// WriteHeaderNow与Flush延迟到加密写出时,避免响应头在设置Endn-Resp之前发出
+type endnRespWriter struct {
+	gin.ResponseWriter
+	buf           *bytes.Buffer
+	headerWritten bool
+}
+
+func (w *endnRespWriter) WriteHeaderNow() {
+	w.headerWritten = true
+}
+
+func (w *endnRespWriter) Flush() {
+	w.headerWritten = true
+}
+
+func (w *endnRespWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+func (w *endnRespWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+func (w *endnRespWriter) Written() bool {
+	return w.buf.Len() > 0 || w.headerWritten || w.ResponseWriter.Written()
+}
+
+func (w *endnRespWriter) Size() int {
+	return w.buf.Len()
+}
+
+func (w *endnRespWriter) flushEncrypted(hexKey string) {
+	if w.buf.Len() == 0 {
+		if w.headerWritten {
+			w.ResponseWriter.WriteHeaderNow()
+		}
+		return
+	}
+	hexIv, err := endnGenHexIv()
+	var encBody []byte
+	if err == nil {
+		encBody, err = EndnEncryptBody(hexKey, hexIv, w.buf.Bytes())
+	}
+	if err != nil {
+		slog.Error(fmt.Sprintf("响应加密失败:%v", err))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+		encBody = []byte(fmt.Sprintf(`{"code":%d,"msg":"响应加密失败"}`, RespCode_Endn_Err))
+	} else {
+		w.Header().Set(EndnHeaderResp, "1")
+		w.Header().Set(EndnHeaderIv, hexIv)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.Write(encBody)
+}
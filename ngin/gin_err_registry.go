@@ -0,0 +1,201 @@
+package ngin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/nerror"
+)
+
+const nGinErrRegistryKey = "NErrRegistry"
+
+// 错误对应的响应
+type NErrMapping struct {
+	// HTTP状态码,0为200
+	HttpStatus int
+	// BaseResp.Code
+	Code int
+	// 消息模板,{err}替换为错误信息,为空时直接使用错误信息
+	Msg string
+	// 自定义消息,参数为匹配到的错误,优先于Msg
+	MsgFunc func(err error) string
+	// 是否打印错误日志及堆栈
+	LogErr bool
+}
+
+type nErrEntry struct {
+	match   func(err error) bool
+	mapping NErrMapping
+}
+
+// 错误到响应的映射
+// 按错误链从外到内(errors.Unwrap及RunTimeErr.SrcErr)查找,同一个错误匹配多条时后注册的优先,都未匹配时使用Fallback
+type NErrRegistry struct {
+	mu       sync.RWMutex
+	entries  []nErrEntry
+	Fallback NErrMapping
+}
+
+// 默认的映射,RecoveryHandlerFunc与Handle使用
+var DefaultErrRegistry = NewNErrRegistry()
+
+// 创建映射,已注册NiexqValidErr与nerror.RunTimeErr
+func NewNErrRegistry() *NErrRegistry {
+	registry := &NErrRegistry{Fallback: NErrMapping{Code: RespCode_UnKnown_Err, LogErr: true}}
+	RegisterErrType[*NiexqValidErr](registry, NErrMapping{Code: RespCode_Valid_Err})
+	registry.RegisterFunc(func(err error) bool {
+		vze, ok := err.(*nerror.RunTimeErr)
+		return ok && vze.SrcErr == nil
+	}, NErrMapping{Code: RespCode_RunTime_Err, MsgFunc: func(err error) string {
+		return err.(*nerror.RunTimeErr).ErrDesc
+	}})
+	registry.RegisterFunc(func(err error) bool {
+		vze, ok := err.(*nerror.RunTimeErr)
+		return ok && vze.SrcErr != nil
+	}, NErrMapping{Code: RespCode_RunTime_Err2, LogErr: true, MsgFunc: func(err error) string {
+		return err.(*nerror.RunTimeErr).ErrDesc
+	}})
+	// 捕获上游的运行时异常,不再打印堆栈
+	registry.RegisterFunc(func(err error) bool {
+		vze, ok := err.(*nerror.RunTimeErr)
+		if !ok {
+			return false
+		}
+		_, srcOk := vze.SrcErr.(*nerror.RunTimeErr)
+		return srcOk
+	}, NErrMapping{Code: RespCode_RunTime_Err2, MsgFunc: func(err error) string {
+		vze := err.(*nerror.RunTimeErr)
+		return fmt.Sprintf("1-%v,2-%v", vze.ErrDesc, vze.SrcErr.Error())
+	}})
+	return registry
+}
+
+// 注册错误类型,错误链中任一错误可断言为T时匹配
+func RegisterErrType[T error](registry *NErrRegistry, mapping NErrMapping) {
+	registry.RegisterFunc(func(err error) bool {
+		_, ok := err.(T)
+		return ok
+	}, mapping)
+}
+
+// 注册哨兵错误,错误链中存在target时匹配(同errors.Is)
+func (registry *NErrRegistry) RegisterSentinel(target error, mapping NErrMapping) {
+	registry.RegisterFunc(func(err error) bool {
+		if err == target {
+			return true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok {
+			return x.Is(target)
+		}
+		return false
+	}, mapping)
+}
+
+// 注册自定义匹配,match只需判断错误链中的单个错误
+func (registry *NErrRegistry) RegisterFunc(match func(err error) bool, mapping NErrMapping) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.entries = append(registry.entries, nErrEntry{match: match, mapping: mapping})
+}
+
+// 查找错误对应的映射,返回映射及匹配到的错误
+func (registry *NErrRegistry) Lookup(err error) (NErrMapping, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	var found *NErrMapping
+	var matched error
+	walkErrChain(err, func(e error) bool {
+		for i := len(registry.entries) - 1; i >= 0; i-- {
+			if registry.entries[i].match(e) {
+				found = &registry.entries[i].mapping
+				matched = e
+				return true
+			}
+		}
+		return false
+	})
+	if found == nil {
+		return registry.Fallback, err
+	}
+	return *found, matched
+}
+
+// 错误转换为HTTP状态码与BaseResp
+func (registry *NErrRegistry) Resolve(err error) (int, *BaseResp, NErrMapping) {
+	mapping, matched := registry.Lookup(err)
+	var msg string
+	switch {
+	case mapping.MsgFunc != nil:
+		msg = mapping.MsgFunc(matched)
+	case mapping.Msg != "":
+		msg = strings.ReplaceAll(mapping.Msg, "{err}", err.Error())
+	default:
+		msg = err.Error()
+	}
+	result := NewErrBaseResp(msg)
+	result.Code = mapping.Code
+	return max(mapping.HttpStatus, http.StatusOK), result, mapping
+}
+
+// 按映射写入错误响应并终止后续处理
+func (registry *NErrRegistry) WriteErr(ctx *gin.Context, err error) {
+	status, result, mapping := registry.Resolve(err)
+	if mapping.LogErr {
+		slog.Error(fmt.Sprintf("%s\t异常:%v\n%s", ctx.Request.URL.Path, err, debug.Stack()))
+	}
+	ctx.AbortWithStatusJSON(status, result)
+}
+
+// 按错误链从外到内遍历,visit返回true时停止
+// nerror.RunTimeErr未实现Unwrap,在此继续遍历SrcErr
+func walkErrChain(err error, visit func(e error) bool) bool {
+	for err != nil {
+		if visit(err) {
+			return true
+		}
+		switch x := err.(type) {
+		case *nerror.RunTimeErr:
+			err = x.SrcErr
+		case interface{ Unwrap() []error }:
+			for _, e := range x.Unwrap() {
+				if walkErrChain(e, visit) {
+					return true
+				}
+			}
+			return false
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// 当前请求使用的映射,RecoveryHandlerFuncWithRegistry设置,未设置时为DefaultErrRegistry
+func GetErrRegistry(ctx *gin.Context) *NErrRegistry {
+	if v, exist := ctx.Get(nGinErrRegistryKey); exist {
+		if registry, ok := v.(*NErrRegistry); ok {
+			return registry
+		}
+	}
+	return DefaultErrRegistry
+}
+
+// 按当前请求的映射写入错误响应,普通gin.HandlerFunc可直接返回错误
+func AbortWithErr(ctx *gin.Context, err error) {
+	GetErrRegistry(ctx).WriteErr(ctx, err)
+}
+
+// panic的值转换为error
+func recoverValue2Err(val any) error {
+	if err, ok := val.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", val)
+}
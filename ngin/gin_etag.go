@@ -0,0 +1,166 @@
+package ngin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BaseResp中每次都会变化的serverTime不参与ETag计算
+var etagServerTimeReg = regexp.MustCompile(`"serverTime":"[^"]*"`)
+
+// GET/HEAD响应的ETag与条件请求
+// 响应为200且未设置ETag时按内容计算弱ETag,If-None-Match匹配时返回304
+// 处理函数通过SetLastModified设置了Last-Modified时,If-Modified-Since不早于它也返回304
+// 响应会先缓存再写出,处理函数调用Flush(SSE等流式响应)后不再处理
+func ETagHandlerFunc() gin.HandlerFunc {
+	slog.Debug("Add Middleware ETagHandlerFunc")
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+			ctx.Next()
+			return
+		}
+		writer := &etagWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		defer func() {
+			ctx.Writer = writer.ResponseWriter
+			writer.finish(ctx.Request)
+		}()
+		ctx.Next()
+	}
+}
+
+// 设置Last-Modified,配合ETagHandlerFunc处理If-Modified-Since
+func SetLastModified(ctx *gin.Context, modTime time.Time) {
+	ctx.Header("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+}
+
+// 根据内容计算弱ETag
+func ContentETag(data []byte) string {
+	h := fnv.New64a()
+	h.Write(etagServerTimeReg.ReplaceAll(data, nil))
+	return fmt.Sprintf(`W/"%x-%x"`, len(data), h.Sum64())
+}
+
+type etagWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if !w.streaming && w.buf.Len() == 0 && w.Header().Get("ETag") != "" && w.Header().Get("Accept-Ranges") != "" {
+		// http.FileServer已自行处理条件请求,不再缓存文件内容
+		w.streaming = true
+	}
+	if w.streaming {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+// 未决定是否304前不提前写出响应头
+func (w *etagWriter) WriteHeaderNow() {
+	if w.streaming {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *etagWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *etagWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.streaming = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *etagWriter) finish(req *http.Request) {
+	if w.streaming {
+		return
+	}
+	header := w.Header()
+	if w.Status() == http.StatusOK {
+		etag := header.Get("ETag")
+		if etag == "" && w.buf.Len() > 0 {
+			etag = ContentETag(w.buf.Bytes())
+			header.Set("ETag", etag)
+		}
+		if notModified(req, etag, header.Get("Last-Modified")) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+	}
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+}
+
+// If-None-Match优先,没有时检查If-Modified-Since
+func notModified(req *http.Request, etag, lastModified string) bool {
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etag == "" {
+			return false
+		}
+		for _, item := range strings.Split(ifNoneMatch, ",") {
+			item = strings.TrimSpace(item)
+			if item == "*" || strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	ifModifiedSince := req.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	modTime, err := http.ParseTime(lastModified)
+	return err == nil && !modTime.After(since)
+}
+
+// 静态文件按修改时间与大小设置ETag,由http.FileServer处理If-None-Match、If-Modified-Since与Range
+func staticETagHandlerFunc(root string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		name := filepath.Join(root, filepath.FromSlash(path.Clean("/"+ctx.Param("filepath"))))
+		info, err := os.Stat(name)
+		if err == nil && info.IsDir() {
+			info, err = os.Stat(filepath.Join(name, "index.html"))
+		}
+		if err == nil && info.Mode().IsRegular() {
+			ctx.Header("ETag", fmt.Sprintf(`W/"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+		}
+		ctx.Next()
+	}
+}
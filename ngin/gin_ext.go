@@ -1,10 +1,18 @@
 package ngin
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	limits "github.com/gin-contrib/size"
 	"github.com/gin-gonic/gin"
@@ -14,6 +22,32 @@ import (
 type NGin struct {
 	GinEngine *gin.Engine
 	NValider  *NValider
+	// 读取整个请求的超时时间,0表示不限制
+	ReadTimeout time.Duration
+	// 读取请求头的超时时间
+	ReadHeaderTimeout time.Duration
+	// 写入响应的超时时间,0表示不限制(文件下载、SSE等长响应需要)
+	WriteTimeout time.Duration
+	// Keep-Alive空闲连接的超时时间
+	IdleTimeout time.Duration
+	// 收到退出信号后等待处理中的请求完成的最长时间,超过后强制关闭
+	ShutdownTimeout time.Duration
+
+	server        *http.Server
+	shutdownHooks []nGinShutdownHook
+	hookMu        sync.Mutex
+	shutdownOnce  sync.Once
+	shutdownErr   error
+	// 通过TypedHandle注册的路由,用于生成接口文档
+	apiRoutes []NGinApiRoute
+	apiMu     sync.Mutex
+	// 超过最大并发被拒绝的请求数
+	maxConcurrentRejected atomic.Uint64
+}
+
+type nGinShutdownHook struct {
+	name string
+	hook func(ctx context.Context) error
 }
 
 func NewNGin() *NGin {
@@ -24,9 +58,18 @@ func NewNGin() *NGin {
 func NewNGinWithMaxConcurrent(maxConcurrent int, maxMultipartMemory, maxBodySize int64, nvalider *NValider) *NGin {
 	gin.SetMode(gin.ReleaseMode)
 
-	ngin := &NGin{GinEngine: gin.New(), NValider: nvalider}
+	ngin := &NGin{
+		GinEngine:         gin.New(),
+		NValider:          nvalider,
+		ReadTimeout:       60 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+	}
 	ngin.GinEngine.MaxMultipartMemory = maxMultipartMemory << 20 // 50MB 内存缓冲区
-	ngin.Use(MaxConcurrentHandlerFunc(maxConcurrent))
+	ngin.Use(MaxConcurrentHandlerFuncWithReject(maxConcurrent, func(c *gin.Context) {
+		ngin.maxConcurrentRejected.Add(1)
+	}))
 	ngin.Use(limits.RequestSizeLimiter(maxBodySize << 20)) // 请求大小限制
 	return ngin
 }
@@ -35,8 +78,9 @@ func (nGin *NGin) Use(middleware ...gin.HandlerFunc) gin.IRoutes {
 	return nGin.GinEngine.Use(middleware...)
 }
 
+// 静态文件,按修改时间与大小设置ETag
 func (nGin *NGin) Static(relativePath, root string) gin.IRoutes {
-	return nGin.GinEngine.StaticFS(relativePath, gin.Dir(root, false))
+	return nGin.GinEngine.Group(relativePath, staticETagHandlerFunc(root)).StaticFS("/", gin.Dir(root, false))
 }
 
 func (nGin *NGin) GET(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
@@ -47,9 +91,136 @@ func (nGin *NGin) POST(relativePath string, handlers ...gin.HandlerFunc) gin.IRo
 	return nGin.GinEngine.POST(relativePath, handlers...)
 }
 
+func (nGin *NGin) PUT(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return nGin.GinEngine.PUT(relativePath, handlers...)
+}
+
+func (nGin *NGin) DELETE(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return nGin.GinEngine.DELETE(relativePath, handlers...)
+}
+
+func (nGin *NGin) PATCH(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return nGin.GinEngine.PATCH(relativePath, handlers...)
+}
+
+func (nGin *NGin) HEAD(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return nGin.GinEngine.HEAD(relativePath, handlers...)
+}
+
+func (nGin *NGin) OPTIONS(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return nGin.GinEngine.OPTIONS(relativePath, handlers...)
+}
+
+func (nGin *NGin) Any(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return nGin.GinEngine.Any(relativePath, handlers...)
+}
+
+func (nGin *NGin) Handle(httpMethod, relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return nGin.GinEngine.Handle(httpMethod, relativePath, handlers...)
+}
+
+// 根路径
+func (nGin *NGin) BasePath() string {
+	return nGin.GinEngine.BasePath()
+}
+
+func (nGin *NGin) ownerNGin() *NGin {
+	return nGin
+}
+
+// 创建路由分组,handlers为分组的中间件
+func (nGin *NGin) Group(relativePath string, handlers ...gin.HandlerFunc) *NGinGroup {
+	return &NGinGroup{GinGroup: nGin.GinEngine.Group(relativePath, handlers...), NGin: nGin}
+}
+
+// 启动HTTP服务并阻塞,收到SIGINT/SIGTERM或调用Shutdown后优雅停机
+// 停机时先等待处理中的请求完成(最多ShutdownTimeout),再按注册的逆序执行停机钩子
 func (nGin *NGin) Run(addr string) (err error) {
 	slog.Info(addr)
-	return nGin.GinEngine.Run(addr)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           nGin.GinEngine,
+		ReadTimeout:       nGin.ReadTimeout,
+		ReadHeaderTimeout: nGin.ReadHeaderTimeout,
+		WriteTimeout:      nGin.WriteTimeout,
+		IdleTimeout:       nGin.IdleTimeout,
+	}
+	nGin.hookMu.Lock()
+	nGin.server = server
+	nGin.hookMu.Unlock()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err = <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			// 其他协程调用了Shutdown,等待其执行完成
+			return nGin.Shutdown()
+		}
+		slog.Error(fmt.Sprintf("HTTP服务异常退出:%v", err))
+		nGin.Shutdown()
+		return err
+	case sig := <-quit:
+		slog.Info(fmt.Sprintf("收到信号[%v],开始优雅停机", sig))
+		return nGin.Shutdown()
+	}
+}
+
+// 注册停机钩子,停机时按注册的逆序执行,先注册的后执行
+// 例如先注册日志再注册数据库,停机时先关闭数据库最后关闭日志
+func (nGin *NGin) OnShutdown(name string, hook func(ctx context.Context) error) {
+	nGin.hookMu.Lock()
+	defer nGin.hookMu.Unlock()
+	nGin.shutdownHooks = append(nGin.shutdownHooks, nGinShutdownHook{name: name, hook: hook})
+}
+
+// 优雅停机,多次调用只执行一次,并发调用时都等待停机完成后返回
+func (nGin *NGin) Shutdown() error {
+	nGin.shutdownOnce.Do(func() {
+		nGin.hookMu.Lock()
+		server := nGin.server
+		nGin.hookMu.Unlock()
+		if server != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), nGin.ShutdownTimeout)
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Error(fmt.Sprintf("等待请求处理完成失败,强制关闭:%v", err))
+				server.Close()
+				nGin.shutdownErr = err
+			}
+			cancel()
+		}
+		nGin.runShutdownHooks()
+		slog.Info("HTTP服务已停止")
+	})
+	return nGin.shutdownErr
+}
+
+func (nGin *NGin) runShutdownHooks() {
+	nGin.hookMu.Lock()
+	hooks := append([]nGinShutdownHook{}, nGin.shutdownHooks...)
+	nGin.hookMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		func(h nGinShutdownHook) {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error(fmt.Sprintf("停机钩子[%s]发生异常:%v", h.name, r))
+				}
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), nGin.ShutdownTimeout)
+			defer cancel()
+			if err := h.hook(ctx); err != nil {
+				slog.Error(fmt.Sprintf("停机钩子[%s]执行失败:%v", h.name, err))
+				return
+			}
+			slog.Debug(fmt.Sprintf("停机钩子[%s]已执行", h.name))
+		}(hooks[i])
+	}
 }
 
 func (nGin *NGin) RouterRedirect(redirectPath string, ctx *gin.Context) {
@@ -72,7 +243,7 @@ func ShouldBindByHeader[T any](headerVo *NiexqGinHeaderVo, ctx *gin.Context, nVa
 func ShouldBind[T any](ctx *gin.Context, nValider *NValider) (*T, error) {
 	obj := new(T)
 	if err := ctx.ShouldBind(obj); err != nil {
-		return nil, nValider.TransErr2ZhErr(err)
+		return nil, nValider.TransCtxErr(ctx, obj, err)
 	}
 	return obj, nil
 }
@@ -80,7 +251,7 @@ func ShouldBind[T any](ctx *gin.Context, nValider *NValider) (*T, error) {
 func ShouldBindJSON[T any](ctx *gin.Context, nValider *NValider) (*T, error) {
 	obj := new(T)
 	if err := ctx.ShouldBindJSON(obj); err != nil {
-		return nil, nValider.TransErr2ZhErr(err)
+		return nil, nValider.TransCtxErr(ctx, obj, err)
 	}
 	return obj, nil
 }
@@ -88,15 +259,14 @@ func ShouldBindJSON[T any](ctx *gin.Context, nValider *NValider) (*T, error) {
 func ReadHeader(ctx *gin.Context, nValider *NValider) (*NiexqGinHeaderVo, error) {
 	obj := &NiexqGinHeaderVo{}
 	if err := ctx.ShouldBindJSON(obj); err != nil {
-		return nil, nValider.TransErr2ZhErr(err)
+		return nil, nValider.TransCtxErr(ctx, obj, err)
 	}
 	return obj, nil
 }
 
 func (nGin *NGin) LogPrintAllRouterInfo() {
-	routers := nGin.GinEngine.Routes()
 	routersInfo := ""
-	for _, v := range routers {
+	for _, v := range nGin.RouteInfos() {
 		routersInfo += "\n" + fmt.Sprintf("%s %s %s", v.Method, v.Path, v.Handler)
 	}
 	slog.Debug(routersInfo)
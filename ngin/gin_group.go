@@ -0,0 +1,112 @@
+package ngin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 路由分组
+type NGinGroup struct {
+	GinGroup *gin.RouterGroup
+	NGin     *NGin
+}
+
+func (group *NGinGroup) Use(middleware ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.Use(middleware...)
+}
+
+// 静态文件,按修改时间与大小设置ETag
+func (group *NGinGroup) Static(relativePath, root string) gin.IRoutes {
+	return group.GinGroup.Group(relativePath, staticETagHandlerFunc(root)).StaticFS("/", gin.Dir(root, false))
+}
+
+func (group *NGinGroup) GET(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.GET(relativePath, handlers...)
+}
+
+func (group *NGinGroup) POST(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.POST(relativePath, handlers...)
+}
+
+func (group *NGinGroup) PUT(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.PUT(relativePath, handlers...)
+}
+
+func (group *NGinGroup) DELETE(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.DELETE(relativePath, handlers...)
+}
+
+func (group *NGinGroup) PATCH(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.PATCH(relativePath, handlers...)
+}
+
+func (group *NGinGroup) HEAD(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.HEAD(relativePath, handlers...)
+}
+
+func (group *NGinGroup) OPTIONS(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.OPTIONS(relativePath, handlers...)
+}
+
+func (group *NGinGroup) Any(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.Any(relativePath, handlers...)
+}
+
+func (group *NGinGroup) Handle(httpMethod, relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return group.GinGroup.Handle(httpMethod, relativePath, handlers...)
+}
+
+// 创建子分组
+func (group *NGinGroup) Group(relativePath string, handlers ...gin.HandlerFunc) *NGinGroup {
+	return &NGinGroup{GinGroup: group.GinGroup.Group(relativePath, handlers...), NGin: group.NGin}
+}
+
+// 分组的完整路径
+func (group *NGinGroup) BasePath() string {
+	return group.GinGroup.BasePath()
+}
+
+func (group *NGinGroup) ownerNGin() *NGin {
+	return group.NGin
+}
+
+// 类型化的处理函数,返回的Resp写入BaseResp.Data,返回的err按GetErrRegistry的映射写入
+type NGinTypedHandler[Req any, Resp any] func(ctx *gin.Context, req *Req) (*Resp, error)
+
+// 将类型化的处理函数适配为gin.HandlerFunc
+// GET/DELETE/HEAD 通过ShouldBind绑定(Query参数),其他方法通过ShouldBindJSON绑定,绑定时使用nValider翻译验证错误
+// 绑定失败与处理函数返回的错误按GetErrRegistry的映射写入,验证失败默认为RespCode_Valid_Err
+func Handle[Req any, Resp any](nValider *NValider, handler NGinTypedHandler[Req, Resp]) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		req, err := bindTypedReq[Req](ctx, nValider)
+		if err != nil {
+			AbortWithErr(ctx, err)
+			return
+		}
+		resp, err := handler(ctx, req)
+		if ctx.Writer.Written() {
+			// 处理函数已自行写入响应
+			return
+		}
+		if err != nil {
+			AbortWithErr(ctx, err)
+			return
+		}
+		if resp == nil {
+			ctx.JSON(http.StatusOK, NewOkBaseResp(EmptyObj{}))
+			return
+		}
+		ctx.JSON(http.StatusOK, NewOkBaseResp(resp))
+	}
+}
+
+// GET/DELETE/HEAD 通常没有请求体,ShouldBindJSON会因空请求体返回EOF,因此按Query参数绑定,字段需设置form标签
+func bindTypedReq[Req any](ctx *gin.Context, nValider *NValider) (*Req, error) {
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead:
+		return ShouldBind[Req](ctx, nValider)
+	default:
+		return ShouldBindJSON[Req](ctx, nValider)
+	}
+}
@@ -20,55 +20,33 @@ import (
 	rediscache "github.com/niexqc/nlibs/ncache/redis_cache"
 	"github.com/niexqc/nlibs/nerror"
 	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/sync/semaphore"
 )
 
 // GinLogger 接收gin框架默认的日志
+// 请求体按DefaultLogMaskKeys脱敏,需要打印响应体或自定义脱敏时使用LoggerHandlerFuncWithConf
 func LoggerHandlerFunc(showReqBody bool) gin.HandlerFunc {
-	slog.Debug("Add Middleware LoggerHandlerFunc")
-	return func(ctx *gin.Context) {
-		start := time.Now()
-		nGinPrintReqLog(ctx, showReqBody)
-		ctx.Next()
-		slog.Info(fmt.Sprintf("Resp:%s\t%v\t%dms", ctx.Request.RequestURI, ctx.Writer.Status(), time.Since(start).Milliseconds()))
-	}
-}
-
-func nGinPrintReqLog(ctx *gin.Context, showReqBody bool) {
-	headerVo := GetHeaderVoFromCtx(ctx)
-	visitTar := headerVo.VisitTar
-	rawQuery := ntools.If3(ctx.Request.URL.RawQuery == "", "Nil_NoRawQuery", ctx.Request.URL.RawQuery)
-	reqMethod := ctx.Request.Method
-	contentType := headerVo.ContentType
-	visitSrc := ntools.If3(headerVo.VisitSrc == "", "No_VisitSrc", headerVo.VisitSrc)
-	clientIp := ctx.ClientIP()
-
-	agentStr := (&ntools.NString{S: ctx.Request.UserAgent()}).CutString(120)
-	agentStr = ntools.If3(agentStr == "", "Nil_UnSetUserAgent", agentStr)
-
-	logStr := fmt.Sprintf("Req:%s\t%s\t%s\t%s\t%s\t%s\t%s", visitTar, rawQuery, reqMethod, contentType, visitSrc, clientIp, agentStr)
-	slog.Debug(logStr)
-	// 打印原始请求参数
-	if showReqBody {
-		reqBodyStr := ""
-		if strings.ContainsAny(contentType, "json") || strings.ContainsAny(contentType, "text") || strings.ContainsAny(contentType, "xml") {
-			reqBodyStr = string(headerVo.ReqBody)
-		} else {
-			reqBodyStr = "Nil_ParseBody"
-		}
-		slog.Info(fmt.Sprintf("Url:%s ReqBody:%s", headerVo.VisitTar, reqBodyStr))
-	}
+	return LoggerHandlerFuncWithConf(&nyaml.YamlConfAccessLog{ShowReqBody: showReqBody, MaskKeys: DefaultLogMaskKeys})
 }
 
 // MaxConcurrentHandlerFunc 控制服务最大承载
 func MaxConcurrentHandlerFunc(max int) gin.HandlerFunc {
+	return MaxConcurrentHandlerFuncWithReject(max, nil)
+}
+
+// MaxConcurrentHandlerFuncWithReject 控制服务最大承载,超过时调用onReject
+func MaxConcurrentHandlerFuncWithReject(max int, onReject func(c *gin.Context)) gin.HandlerFunc {
 	slog.Debug("Add Middleware MaxConcurrentHandlerFunc")
 	sem := semaphore.NewWeighted(int64(max))
 	return func(c *gin.Context) {
 		// 尝试获取信号量,非阻塞模式，获取失败直接返回错误
 		if !sem.TryAcquire(1) {
+			if onReject != nil {
+				onReject(c)
+			}
 			c.AbortWithStatusJSON(429, gin.H{"error": "服务繁忙，请稍后重试"})
 			return
 		}
@@ -78,69 +56,79 @@ func MaxConcurrentHandlerFunc(max int) gin.HandlerFunc {
 	}
 }
 
-// Recovery recover掉项目可能出现的错误
+// Recovery recover掉项目可能出现的错误,使用DefaultErrRegistry转换为响应
 func RecoveryHandlerFunc() gin.HandlerFunc {
+	return RecoveryHandlerFuncWithRegistry(DefaultErrRegistry)
+}
+
+// Recovery recover掉项目可能出现的错误,使用registry转换为响应
+// 处理函数通过ctx.Error记录错误且未写入响应时,同样按registry写入
+func RecoveryHandlerFuncWithRegistry(registry *NErrRegistry) gin.HandlerFunc {
 	slog.Debug("Add Middleware RecoveryHandlerFunc")
 	return func(c *gin.Context) {
-		defer recoveryErrorWork(c)
+		c.Set(nGinErrRegistryKey, registry)
+		defer recoveryErrorWork(c, registry)
 		c.Next()
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			registry.WriteErr(c, c.Errors.Last().Err)
+		}
 	}
 }
 
 // 日志跟踪ID生成
 func TraceIdGenHandlerFunc(traceIdPrefix string, redisService *rediscache.RedisService) gin.HandlerFunc {
 	slog.Debug("Add Middleware TraceIdGenHandlerFunc")
-	return func(c *gin.Context) {
-		timeStr := time.Now().Format("20060102T150405")
-		redisKeyStr := traceIdPrefix + timeStr
-		keySeqNo, err := redisService.Int64Incr(redisKeyStr, 1200)
-		if err != nil {
-			slog.Error("无法生成日志跟踪编号", "err", err)
-			panic(nerror.NewRunTimeErrorFmt("无法生成日志跟踪编号:%v", err.Error()))
-		}
-		traceId := fmt.Sprintf("%s%04d", redisKeyStr, keySeqNo)
-		ntools.SlogSetTraceId(traceId)
-		c.Next()
-	}
+	return traceIdHandlerFunc(func() string {
+		return genTraceIdBySeq(traceIdPrefix, redisService.Int64Incr)
+	})
 }
 
 // 日志跟踪ID生成 - MemCacheService
 func TraceIdGenByMemCacheHandlerFunc(traceIdPrefix string, memCacheService *mencache.MemCacheService) gin.HandlerFunc {
 	slog.Debug("Add Middleware TraceIdGenHandlerFunc")
+	return traceIdHandlerFunc(func() string {
+		return genTraceIdBySeq(traceIdPrefix, memCacheService.Int64Incr)
+	})
+}
+
+// 日志跟踪ID,genTraceId为nil时使用W3C trace-id
+func TraceIdHandlerFunc(genTraceId func() string) gin.HandlerFunc {
+	slog.Debug("Add Middleware TraceIdHandlerFunc")
+	if genTraceId == nil {
+		genTraceId = ntools.NewW3cTraceId
+	}
+	return traceIdHandlerFunc(genTraceId)
+}
+
+// 优先使用请求的X-Trace-Id与traceparent,都没有时生成,并在响应头中返回
+func traceIdHandlerFunc(genTraceId func() string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		timeStr := time.Now().Format("20060102T150405")
-		redisKeyStr := traceIdPrefix + timeStr
-		keySeqNo, err := memCacheService.Int64Incr(redisKeyStr, 1200)
-		if err != nil {
-			slog.Error("无法生成日志跟踪编号", "err", err)
-			panic(nerror.NewRunTimeErrorFmt("无法生成日志跟踪编号:%v", err.Error()))
+		traceId, w3cTraceId := ntools.TraceFromCarrier(c.GetHeader(ntools.TraceIdHeader), c.GetHeader(ntools.TraceParentHeader))
+		if traceId == "" {
+			traceId = genTraceId()
 		}
-		traceId := fmt.Sprintf("%s%04d", redisKeyStr, keySeqNo)
-		ntools.SlogSetTraceId(traceId)
+		ntools.SlogSetTraceContext(traceId, w3cTraceId)
+		c.Header(ntools.TraceIdHeader, traceId)
+		c.Header(ntools.TraceParentHeader, ntools.TraceParentStr())
 		c.Next()
 	}
 }
 
+// 前缀+时间+秒内序号
+func genTraceIdBySeq(traceIdPrefix string, incr func(key string, expireMillisecond int64) (int64, error)) string {
+	timeStr := time.Now().Format("20060102T150405")
+	redisKeyStr := traceIdPrefix + timeStr
+	keySeqNo, err := incr(redisKeyStr, 1200)
+	if err != nil {
+		slog.Error("无法生成日志跟踪编号", "err", err)
+		panic(nerror.NewRunTimeErrorFmt("无法生成日志跟踪编号:%v", err.Error()))
+	}
+	return fmt.Sprintf("%s%04d", redisKeyStr, keySeqNo)
+}
+
 // Header读取并设置
 func HeaderSetHandlerFunc() gin.HandlerFunc {
 	slog.Debug("Add Middleware HeaderSetHandlerFunc")
-
-	readAndResetBody := func(c *gin.Context) []byte {
-		// 1. 读取原始 Body 内容
-		body, err := c.GetRawData()
-		if err != nil {
-			c.AbortWithError(http.StatusBadRequest, err)
-			return body
-		}
-		// 2. 重写 GetBody 方法（关键！）
-		c.Request.GetBody = func() (io.ReadCloser, error) {
-			return io.NopCloser(bytes.NewBuffer(body)), nil
-		}
-		// 3. 重置 Body 供后续使用
-		c.Request.Body, _ = c.Request.GetBody()
-		return body
-	}
-
 	return func(ctx *gin.Context) {
 		ginHeaders := ctx.Request.Header
 		heaerVo := NiexqGinHeaderVo{}
@@ -169,6 +157,23 @@ func HeaderSetHandlerFunc() gin.HandlerFunc {
 	}
 }
 
+// 读取请求的原始Body并重置,后续仍可再次读取
+func readAndResetBody(c *gin.Context) []byte {
+	// 1. 读取原始 Body 内容
+	body, err := c.GetRawData()
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return body
+	}
+	// 2. 重写 GetBody 方法（关键！）
+	c.Request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewBuffer(body)), nil
+	}
+	// 3. 重置 Body 供后续使用
+	c.Request.Body, _ = c.Request.GetBody()
+	return body
+}
+
 func GetHeaderVoFromCtx(c *gin.Context) *NiexqGinHeaderVo {
 	if v, exist := c.Get(reflect.TypeOf(NiexqGinHeaderVo{}).Name()); exist {
 		if ne, ok := v.(*NiexqGinHeaderVo); ok {
@@ -178,7 +183,7 @@ func GetHeaderVoFromCtx(c *gin.Context) *NiexqGinHeaderVo {
 	return &NiexqGinHeaderVo{}
 }
 
-func recoveryErrorWork(c *gin.Context) {
+func recoveryErrorWork(c *gin.Context, registry *NErrRegistry) {
 	reqPath := c.Request.URL.Path
 	if err := recover(); err != nil {
 		//检查连接是否已断开
@@ -190,8 +195,8 @@ func recoveryErrorWork(c *gin.Context) {
 				}
 			}
 		}
-		httpRequest, _ := httputil.DumpRequest(c.Request, false)
 		if brokenPipe {
+			httpRequest, _ := httputil.DumpRequest(c.Request, false)
 			//发生了异常，但是连接已断开
 			slog.Error(fmt.Sprintf("%s\t异常:%v\n%s\n%s", reqPath, err, string(httpRequest), "连接已断开"))
 			c.Error(err.(error)) // nolint: errcheck
@@ -199,32 +204,12 @@ func recoveryErrorWork(c *gin.Context) {
 			return
 		}
 		//这里把错误输出出去
-		if vze, ok := err.(*NiexqValidErr); ok {
-			result := NewErrBaseResp(fmt.Sprintf("%v", vze.Error()))
-			result.Code = RespCode_Valid_Err
-			c.JSON(http.StatusOK, &result)
-		} else if vze, ok := err.(*nerror.RunTimeErr); ok {
-			if vze.SrcErr == nil {
-				result := NewErrBaseResp(fmt.Sprintf("%v", vze.ErrDesc))
-				result.Code = RespCode_RunTime_Err
-				c.JSON(http.StatusOK, &result)
-			} else {
-				errStr := fmt.Sprintf("%v", vze.ErrDesc)
-				if vze1, ok := vze.SrcErr.(*nerror.RunTimeErr); ok {
-					errStr = fmt.Sprintf("1-%v,2-%v", errStr, vze1.Error())
-				} else {
-					slog.Error(fmt.Sprintf("%s\t异常:%v\n%s\n%s", reqPath, err, string(httpRequest), debug.Stack()))
-				}
-				result := NewErrBaseResp(errStr)
-				result.Code = RespCode_RunTime_Err2
-				c.JSON(http.StatusOK, &result)
-			}
-		} else {
+		panicErr := recoverValue2Err(err)
+		status, result, mapping := registry.Resolve(panicErr)
+		if mapping.LogErr {
+			httpRequest, _ := httputil.DumpRequest(c.Request, false)
 			slog.Error(fmt.Sprintf("%s\t异常:%v\n%s\n%s", reqPath, err, string(httpRequest), debug.Stack()))
-			result := NewErrBaseResp(fmt.Sprintf("%v", err))
-			result.Code = RespCode_UnKnown_Err
-			c.JSON(http.StatusOK, &result)
 		}
-		c.Abort()
+		c.AbortWithStatusJSON(status, result)
 	}
 }
@@ -0,0 +1,144 @@
+package ngin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ndnen"
+	"github.com/niexqc/nlibs/njson"
+)
+
+const (
+	idempotencyStateProcessing = "processing"
+	idempotencyStateDone       = "done"
+	// 重放的响应会带上该响应头
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// 幂等键的存储,RedisService 与 MemCacheService 均已实现
+type NIdempotencyCache interface {
+	// 仅在key不存在时写入,key已存在返回false,err表示缓存不可用
+	TryPutNxExStr(key string, val string, sencond int) (bool, error)
+	GetStr(key string) (string, error)
+	PutExStr(key string, val string, sencond int) error
+	ClearKey(key string) error
+}
+
+type idempotencyRecord struct {
+	State string `json:"state"`
+	// 请求方法、路径与Body的SM3,同一个幂等键只能用于相同的请求
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Body        string `json:"body"`
+}
+
+// 幂等请求,读取Idempotency-Key,没有时读取Onece-Str,都没有时不处理
+// 首次请求占用幂等键(processingTtl秒),处理完成且BaseResp.Code为0时保存响应(ttl秒),其他结果释放幂等键以便重试
+// 幂等键处理中时返回RespCode_Idem_Processing,已完成时重放保存的响应
+// 幂等键按登录用户(AuthHandlerFunc之后使用时)、请求方法与路径区分
+// 缓存不可用时与限流一致,记录日志后放行,本次请求不做幂等处理
+func IdempotencyHandlerFunc(cache NIdempotencyCache, keyPrefix string, ttl, processingTtl int) gin.HandlerFunc {
+	slog.Debug("Add Middleware IdempotencyHandlerFunc")
+	return func(ctx *gin.Context) {
+		idemKey := ctx.GetHeader("Idempotency-Key")
+		if idemKey == "" {
+			idemKey = ctx.GetHeader("Onece-Str")
+		}
+		if idemKey == "" {
+			ctx.Next()
+			return
+		}
+		userId := ""
+		if principal, ok := GetPrincipal(ctx); ok {
+			userId = principal.UserId
+		}
+		body := readAndResetBody(ctx)
+		if ctx.IsAborted() {
+			return
+		}
+		cacheKey := fmt.Sprintf("%s%s:%s:%s:%s", keyPrefix, userId, ctx.Request.Method, ctx.FullPath(), idemKey)
+		fingerprint := hex.EncodeToString(ndnen.Sm3hash([]byte(ctx.Request.Method + "\n" + ctx.Request.URL.RequestURI() + "\n" + string(body))))
+
+		processing := njson.Obj2StrWithPanicError(idempotencyRecord{State: idempotencyStateProcessing, Fingerprint: fingerprint})
+		ok, err := cache.TryPutNxExStr(cacheKey, processing, processingTtl)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("幂等键[%s]占用失败,本次放行:%v", cacheKey, err))
+			ctx.Next()
+			return
+		}
+		if !ok {
+			idempotencyReplay(ctx, cache, cacheKey, fingerprint)
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		completed := false
+		defer func() {
+			ctx.Writer = writer.ResponseWriter
+			if !completed {
+				// panic等未正常完成时释放幂等键
+				cache.ClearKey(cacheKey)
+			}
+		}()
+		ctx.Next()
+		completed = true
+
+		result, err := njson.Bytes2Obj[BaseResp](writer.buf.Bytes())
+		if writer.Status() != http.StatusOK || err != nil || result.Code != RespCode_OK {
+			cache.ClearKey(cacheKey)
+			return
+		}
+		done := idempotencyRecord{State: idempotencyStateDone, Fingerprint: fingerprint, Status: writer.Status(),
+			ContentType: writer.Header().Get("Content-Type"), Body: writer.buf.String()}
+		if err := cache.PutExStr(cacheKey, njson.Obj2StrWithPanicError(done), ttl); err != nil {
+			slog.Error(fmt.Sprintf("保存幂等响应失败:%v", err))
+		}
+	}
+}
+
+// 幂等键已被占用时,处理中返回RespCode_Idem_Processing,已完成时重放响应
+func idempotencyReplay(ctx *gin.Context, cache NIdempotencyCache, cacheKey, fingerprint string) {
+	recordStr, err := cache.GetStr(cacheKey)
+	if err != nil || recordStr == "" {
+		ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Idem_Processing, "请求处理中,请勿重复提交"))
+		return
+	}
+	record, err := njson.Str2Obj[idempotencyRecord](recordStr)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Idem_Processing, "请求处理中,请勿重复提交"))
+		return
+	}
+	if record.Fingerprint != fingerprint {
+		ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Valid_Err, "幂等键已用于其他请求"))
+		return
+	}
+	if record.State != idempotencyStateDone {
+		ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Idem_Processing, "请求处理中,请勿重复提交"))
+		return
+	}
+	ctx.Header(IdempotencyReplayedHeader, "true")
+	ctx.Data(record.Status, record.ContentType, []byte(record.Body))
+	ctx.Abort()
+}
+
+// 写出响应的同时保留一份
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.buf.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.buf.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,197 @@
+package ngin
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/niexqc/nlibs/ndnen"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+const (
+	JwtAlgHS256 = "HS256"
+	JwtAlgRS256 = "RS256"
+	// SM3WithSM2,签名为ASN.1 DER
+	JwtAlgSM2 = "SM2"
+)
+
+// JWT的签发与验证,只接受创建时指定的算法
+type NJwt struct {
+	alg     string
+	hmacKey []byte
+	rsaPri  *rsa.PrivateKey
+	rsaPub  *rsa.PublicKey
+	sm2Pri  *sm2.PrivateKey
+	sm2Pub  *sm2.PublicKey
+	//签发者,不为空时验证iss
+	Issuer string
+	//验证exp/nbf时允许的时间误差
+	Leeway time.Duration
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Sub   string         `json:"sub"`
+	Name  string         `json:"name,omitempty"`
+	Roles []string       `json:"roles,omitempty"`
+	Perms []string       `json:"perms,omitempty"`
+	Extra map[string]any `json:"ext,omitempty"`
+	Iss   string         `json:"iss,omitempty"`
+	Iat   int64          `json:"iat"`
+	Nbf   int64          `json:"nbf,omitempty"`
+	Exp   int64          `json:"exp"`
+}
+
+// HMAC-SHA256签名的JWT
+func NewJwtHmac(key []byte) *NJwt {
+	return &NJwt{alg: JwtAlgHS256, hmacKey: key}
+}
+
+// RSA-SHA256签名的JWT,只验证时pri可为nil
+func NewJwtRsa(pri *rsa.PrivateKey, pub *rsa.PublicKey) *NJwt {
+	if pub == nil && pri != nil {
+		pub = &pri.PublicKey
+	}
+	return &NJwt{alg: JwtAlgRS256, rsaPri: pri, rsaPub: pub}
+}
+
+// SM2签名的JWT,只验证时pri可为nil
+func NewJwtSm2(pri *sm2.PrivateKey, pub *sm2.PublicKey) *NJwt {
+	if pub == nil && pri != nil {
+		pub = &pri.PublicKey
+	}
+	return &NJwt{alg: JwtAlgSM2, sm2Pri: pri, sm2Pub: pub}
+}
+
+// 签发Token
+func (j *NJwt) Sign(principal *NGinPrincipal, expire time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwtClaims{
+		Sub:   principal.UserId,
+		Name:  principal.UserName,
+		Roles: principal.Roles,
+		Perms: principal.Perms,
+		Extra: principal.Extra,
+		Iss:   j.Issuer,
+		Iat:   now.Unix(),
+		Exp:   now.Add(expire).Unix(),
+	}
+	headerBytes, _ := json.Marshal(jwtHeader{Alg: j.alg, Typ: "JWT"})
+	claimsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := jwtEncode(headerBytes) + "." + jwtEncode(claimsBytes)
+	sign, err := j.sign(signingInput)
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + jwtEncode(sign), nil
+}
+
+// 验证Token并返回用户信息
+func (j *NJwt) Verify(token string) (*NGinPrincipal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nerror.NewRunTimeError("Token格式错误")
+	}
+	header := jwtHeader{}
+	if err := jwtDecodeJson(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != j.alg {
+		return nil, nerror.NewRunTimeErrorFmt("不支持的签名算法[%s]", header.Alg)
+	}
+	sign, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nerror.NewRunTimeError("Token签名格式错误")
+	}
+	if !j.verify(parts[0]+"."+parts[1], sign) {
+		return nil, nerror.NewRunTimeError("Token签名错误")
+	}
+	claims := jwtClaims{}
+	if err := jwtDecodeJson(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if claims.Exp > 0 && now.After(time.Unix(claims.Exp, 0).Add(j.Leeway)) {
+		return nil, nerror.NewRunTimeError("Token已过期")
+	}
+	if claims.Nbf > 0 && now.Before(time.Unix(claims.Nbf, 0).Add(-j.Leeway)) {
+		return nil, nerror.NewRunTimeError("Token未生效")
+	}
+	if j.Issuer != "" && claims.Iss != j.Issuer {
+		return nil, nerror.NewRunTimeError("Token签发者错误")
+	}
+	return &NGinPrincipal{
+		UserId:    claims.Sub,
+		UserName:  claims.Name,
+		Roles:     claims.Roles,
+		Perms:     claims.Perms,
+		Extra:     claims.Extra,
+		ExpiresAt: time.Unix(claims.Exp, 0),
+	}, nil
+}
+
+func (j *NJwt) sign(signingInput string) ([]byte, error) {
+	switch j.alg {
+	case JwtAlgHS256:
+		h := hmac.New(sha256.New, j.hmacKey)
+		h.Write([]byte(signingInput))
+		return h.Sum(nil), nil
+	case JwtAlgRS256:
+		if j.rsaPri == nil {
+			return nil, nerror.NewRunTimeError("未设置RSA私钥,不能签发Token")
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.SignPKCS1v15(rand.Reader, j.rsaPri, crypto.SHA256, digest[:])
+	case JwtAlgSM2:
+		if j.sm2Pri == nil {
+			return nil, nerror.NewRunTimeError("未设置SM2私钥,不能签发Token")
+		}
+		return base64.StdEncoding.DecodeString(ndnen.Sm2SignByPriKey(j.sm2Pri, signingInput))
+	}
+	return nil, nerror.NewRunTimeErrorFmt("不支持的签名算法[%s]", j.alg)
+}
+
+func (j *NJwt) verify(signingInput string, sign []byte) bool {
+	switch j.alg {
+	case JwtAlgHS256:
+		h := hmac.New(sha256.New, j.hmacKey)
+		h.Write([]byte(signingInput))
+		return hmac.Equal(h.Sum(nil), sign)
+	case JwtAlgRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		return j.rsaPub != nil && rsa.VerifyPKCS1v15(j.rsaPub, crypto.SHA256, digest[:], sign) == nil
+	case JwtAlgSM2:
+		return j.sm2Pub != nil && ndnen.Sm2VerifyByPubKey(j.sm2Pub, signingInput, base64.StdEncoding.EncodeToString(sign))
+	}
+	return false
+}
+
+func jwtEncode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func jwtDecodeJson(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return nerror.NewRunTimeError("Token格式错误")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nerror.NewRunTimeError("Token格式错误")
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package ngin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 默认的耗时分桶-秒
+var DefaultMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type nGinMetricsKey struct {
+	method string
+	route  string
+	status int
+}
+
+type nGinHistogram struct {
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// 请求指标,以Prometheus文本格式输出
+type NGinMetrics struct {
+	namespace string
+	buckets   []float64
+	mu        sync.Mutex
+	requests  map[nGinMetricsKey]*nGinHistogram
+	inFlight  atomic.Int64
+	nGin      *NGin
+	startTime time.Time
+}
+
+// 开启请求指标并在metricsPath输出
+// 通过Use添加中间件,只统计之后注册的路由,需在注册业务路由之前调用
+// namespace 指标名称的前缀,为空时为ngin
+// buckets 耗时分桶-秒,为空时使用DefaultMetricsBuckets
+func (nGin *NGin) EnableMetrics(metricsPath, namespace string, buckets ...float64) *NGinMetrics {
+	if routes := nGin.GinEngine.Routes(); len(routes) > 0 {
+		slog.Warn(fmt.Sprintf("EnableMetrics在注册路由之后调用,已注册的%d个路由不会统计", len(routes)))
+	}
+	if namespace == "" {
+		namespace = "ngin"
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	metrics := &NGinMetrics{
+		namespace: namespace,
+		buckets:   buckets,
+		requests:  map[nGinMetricsKey]*nGinHistogram{},
+		nGin:      nGin,
+		startTime: time.Now(),
+	}
+	nGin.Use(metrics.HandlerFunc())
+	nGin.GET(metricsPath, func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(metrics.Export()))
+	})
+	return metrics
+}
+
+// 记录请求数、耗时与处理中的请求数,路由按注册时的模板(/user/:id)统计
+func (m *NGinMetrics) HandlerFunc() gin.HandlerFunc {
+	slog.Debug("Add Middleware MetricsHandlerFunc")
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		m.inFlight.Add(1)
+		defer func() {
+			m.inFlight.Add(-1)
+			route := ctx.FullPath()
+			if route == "" {
+				// 未匹配的路由统一统计,避免指标数量无限增长
+				route = "unmatched"
+			}
+			m.observe(nGinMetricsKey{method: ctx.Request.Method, route: route, status: ctx.Writer.Status()}, time.Since(start).Seconds())
+		}()
+		ctx.Next()
+	}
+}
+
+func (m *NGinMetrics) observe(key nGinMetricsKey, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.requests[key]
+	if !ok {
+		h = &nGinHistogram{buckets: make([]uint64, len(m.buckets))}
+		m.requests[key] = h
+	}
+	for i, le := range m.buckets {
+		if seconds <= le {
+			h.buckets[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// Prometheus文本格式的指标
+func (m *NGinMetrics) Export() string {
+	m.mu.Lock()
+	keys := make([]nGinMetricsKey, 0, len(m.requests))
+	histograms := make(map[nGinMetricsKey]nGinHistogram, len(m.requests))
+	for k, h := range m.requests {
+		keys = append(keys, k)
+		histograms[k] = nGinHistogram{buckets: append([]uint64{}, h.buckets...), sum: h.sum, count: h.count}
+	}
+	m.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	sb := &strings.Builder{}
+	name := m.namespace + "_http_requests_total"
+	writeMetricHead(sb, name, "counter", "请求总数")
+	for _, k := range keys {
+		fmt.Fprintf(sb, "%s{%s} %d\n", name, k.labels(), histograms[k].count)
+	}
+
+	name = m.namespace + "_http_request_duration_seconds"
+	writeMetricHead(sb, name, "histogram", "请求耗时-秒")
+	for _, k := range keys {
+		h := histograms[k]
+		labels := k.labels()
+		for i, le := range m.buckets {
+			fmt.Fprintf(sb, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatMetricFloat(le), h.buckets[i])
+		}
+		fmt.Fprintf(sb, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(sb, "%s_sum{%s} %s\n", name, labels, formatMetricFloat(h.sum))
+		fmt.Fprintf(sb, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+
+	name = m.namespace + "_http_requests_in_flight"
+	writeMetricHead(sb, name, "gauge", "处理中的请求数")
+	fmt.Fprintf(sb, "%s %d\n", name, m.inFlight.Load())
+
+	name = m.namespace + "_http_max_concurrent_rejected_total"
+	writeMetricHead(sb, name, "counter", "超过最大并发被拒绝的请求数")
+	fmt.Fprintf(sb, "%s %d\n", name, m.nGin.maxConcurrentRejected.Load())
+
+	writeRuntimeMetrics(sb, m.startTime)
+	return sb.String()
+}
+
+func writeRuntimeMetrics(sb *strings.Builder, startTime time.Time) {
+	memStats := &runtime.MemStats{}
+	runtime.ReadMemStats(memStats)
+	gauges := []struct {
+		name, help string
+		value      float64
+	}{
+		{"go_goroutines", "协程数", float64(runtime.NumGoroutine())},
+		{"go_threads", "操作系统线程数", float64(threadCount())},
+		{"go_memstats_alloc_bytes", "已分配且仍在使用的堆内存", float64(memStats.Alloc)},
+		{"go_memstats_sys_bytes", "从操作系统获取的内存", float64(memStats.Sys)},
+		{"go_memstats_heap_inuse_bytes", "使用中的堆内存", float64(memStats.HeapInuse)},
+		{"go_memstats_heap_objects", "堆上的对象数", float64(memStats.HeapObjects)},
+		{"go_memstats_next_gc_bytes", "下次GC的堆内存阈值", float64(memStats.NextGC)},
+		{"process_start_time_seconds", "启动时间", float64(startTime.Unix())},
+	}
+	for _, g := range gauges {
+		writeMetricHead(sb, g.name, "gauge", g.help)
+		fmt.Fprintf(sb, "%s %s\n", g.name, formatMetricFloat(g.value))
+	}
+	counters := []struct {
+		name, help string
+		value      float64
+	}{
+		{"go_memstats_alloc_bytes_total", "累计分配的堆内存", float64(memStats.TotalAlloc)},
+		{"go_memstats_mallocs_total", "累计分配的对象数", float64(memStats.Mallocs)},
+		{"go_gc_cycles_total", "累计GC次数", float64(memStats.NumGC)},
+		{"go_gc_pause_seconds_total", "累计GC暂停时间", float64(memStats.PauseTotalNs) / 1e9},
+	}
+	for _, c := range counters {
+		writeMetricHead(sb, c.name, "counter", c.help)
+		fmt.Fprintf(sb, "%s %s\n", c.name, formatMetricFloat(c.value))
+	}
+}
+
+func threadCount() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
+
+func writeMetricHead(sb *strings.Builder, name, metricType, help string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func (k nGinMetricsKey) labels() string {
+	return fmt.Sprintf(`method="%s",route="%s",status="%d"`, escapeMetricLabel(k.method), escapeMetricLabel(k.route), k.status)
+}
+
+var metricLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeMetricLabel(val string) string {
+	return metricLabelReplacer.Replace(val)
+}
+
+func formatMetricFloat(val float64) string {
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
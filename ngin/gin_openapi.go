@@ -0,0 +1,502 @@
+package ngin
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ndb/sqlext"
+	"gopkg.in/yaml.v3"
+)
+
+// NGin与NGinGroup均已实现,用于TypedHandle注册路由
+type NGinRouter interface {
+	Handle(httpMethod, relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes
+	BasePath() string
+	ownerNGin() *NGin
+}
+
+// 通过TypedHandle注册的路由信息
+type NGinApiRoute struct {
+	Method string
+	// 完整路径,gin格式 /user/:id
+	Path    string
+	Summary string
+	// 所在分组的路径,作为文档的Tag
+	Tag      string
+	ReqType  reflect.Type
+	RespType reflect.Type
+}
+
+// 注册类型化的路由并记录到接口文档
+// summary 接口说明
+// middleware 在处理函数之前执行的中间件
+func TypedHandle[Req any, Resp any](router NGinRouter, httpMethod, relativePath, summary string, handler NGinTypedHandler[Req, Resp], middleware ...gin.HandlerFunc) gin.IRoutes {
+	nGin := router.ownerNGin()
+	tag := router.BasePath()
+	if tag == "/" {
+		tag = "default"
+	}
+	route := NGinApiRoute{
+		Method:   strings.ToUpper(httpMethod),
+		Path:     joinRoutePath(router.BasePath(), relativePath),
+		Summary:  summary,
+		Tag:      tag,
+		ReqType:  reflect.TypeOf((*Req)(nil)).Elem(),
+		RespType: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+	nGin.apiMu.Lock()
+	nGin.apiRoutes = append(nGin.apiRoutes, route)
+	nGin.apiMu.Unlock()
+	handlers := append(append([]gin.HandlerFunc{}, middleware...), Handle(nGin.NValider, handler))
+	return router.Handle(route.Method, relativePath, handlers...)
+}
+
+func TypedGET[Req any, Resp any](router NGinRouter, relativePath, summary string, handler NGinTypedHandler[Req, Resp], middleware ...gin.HandlerFunc) gin.IRoutes {
+	return TypedHandle(router, http.MethodGet, relativePath, summary, handler, middleware...)
+}
+
+func TypedPOST[Req any, Resp any](router NGinRouter, relativePath, summary string, handler NGinTypedHandler[Req, Resp], middleware ...gin.HandlerFunc) gin.IRoutes {
+	return TypedHandle(router, http.MethodPost, relativePath, summary, handler, middleware...)
+}
+
+func TypedPUT[Req any, Resp any](router NGinRouter, relativePath, summary string, handler NGinTypedHandler[Req, Resp], middleware ...gin.HandlerFunc) gin.IRoutes {
+	return TypedHandle(router, http.MethodPut, relativePath, summary, handler, middleware...)
+}
+
+func TypedDELETE[Req any, Resp any](router NGinRouter, relativePath, summary string, handler NGinTypedHandler[Req, Resp], middleware ...gin.HandlerFunc) gin.IRoutes {
+	return TypedHandle(router, http.MethodDelete, relativePath, summary, handler, middleware...)
+}
+
+func joinRoutePath(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	finalPath := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
+
+// 通过TypedHandle注册的所有路由
+func (nGin *NGin) ApiRoutes() []NGinApiRoute {
+	nGin.apiMu.Lock()
+	defer nGin.apiMu.Unlock()
+	return append([]NGinApiRoute{}, nGin.apiRoutes...)
+}
+
+// 根据TypedHandle注册的路由生成OpenAPI 3文档
+func (nGin *NGin) OpenApiDoc(title, version string) map[string]any {
+	builder := &openApiBuilder{schemas: map[string]any{}, names: map[reflect.Type]string{}}
+	baseRespRef := builder.schemaOf(reflect.TypeOf(BaseResp{}))
+	paths := map[string]any{}
+	for _, route := range nGin.ApiRoutes() {
+		apiPath, pathParams := openApiPath(route.Path)
+		operation := map[string]any{
+			"tags":        []string{route.Tag},
+			"summary":     route.Summary,
+			"operationId": openApiOperationId(route.Method, route.Path),
+		}
+		params := []any{}
+		for _, name := range pathParams {
+			params = append(params, map[string]any{"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		switch route.Method {
+		case http.MethodGet, http.MethodDelete, http.MethodHead:
+			params = append(params, builder.queryParams(route.ReqType, pathParams)...)
+		default:
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": builder.schemaOf(route.ReqType)}},
+			}
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		respSchema := map[string]any{"allOf": []any{
+			baseRespRef,
+			map[string]any{"type": "object", "properties": map[string]any{"data": builder.schemaOf(route.RespType)}},
+		}}
+		operation["responses"] = map[string]any{
+			"200": map[string]any{
+				"description": "code为0时成功,其他为失败",
+				"content":     map[string]any{"application/json": map[string]any{"schema": respSchema}},
+			},
+		}
+		pathItem, ok := paths[apiPath].(map[string]any)
+		if !ok {
+			pathItem = map[string]any{}
+			paths[apiPath] = pathItem
+		}
+		pathItem[strings.ToLower(route.Method)] = operation
+	}
+	return map[string]any{
+		"openapi":    "3.0.3",
+		"info":       map[string]any{"title": title, "version": version},
+		"paths":      paths,
+		"components": map[string]any{"schemas": builder.schemas},
+	}
+}
+
+// 在docPath提供接口文档,docPath以.yaml或.yml结尾时返回YAML,否则返回JSON
+// 文档在第一次访问时生成,需在此之前注册完路由
+func (nGin *NGin) ServeOpenApi(docPath, title, version string) {
+	isYaml := strings.HasSuffix(docPath, ".yaml") || strings.HasSuffix(docPath, ".yml")
+	var once sync.Once
+	var docBytes []byte
+	var docErr error
+	nGin.GET(docPath, func(ctx *gin.Context) {
+		once.Do(func() {
+			doc := nGin.OpenApiDoc(title, version)
+			if isYaml {
+				docBytes, docErr = yaml.Marshal(doc)
+			} else {
+				docBytes, docErr = json.Marshal(doc)
+			}
+		})
+		if docErr != nil {
+			ctx.JSON(http.StatusOK, NewErrBaseResp("生成接口文档失败:"+docErr.Error()))
+			return
+		}
+		if isYaml {
+			ctx.Data(http.StatusOK, "application/yaml; charset=utf-8", docBytes)
+			return
+		}
+		ctx.Data(http.StatusOK, "application/json; charset=utf-8", docBytes)
+	})
+}
+
+var openApiPathParamReg = regexp.MustCompile(`[:*]([^/]+)`)
+
+// gin路径转换为OpenAPI路径 /user/:id => /user/{id}
+func openApiPath(ginPath string) (string, []string) {
+	params := []string{}
+	apiPath := openApiPathParamReg.ReplaceAllStringFunc(ginPath, func(s string) string {
+		params = append(params, s[1:])
+		return "{" + s[1:] + "}"
+	})
+	return apiPath, params
+}
+
+var openApiNameReg = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+func openApiOperationId(method, ginPath string) string {
+	return strings.ToLower(method) + "_" + strings.Trim(openApiNameReg.ReplaceAllString(ginPath, "_"), "_")
+}
+
+// 特殊类型的Schema,按JSON序列化后的类型描述
+var openApiTypeSchemas = map[reflect.Type]map[string]any{
+	reflect.TypeOf(time.Time{}):          {"type": "string", "format": "date-time"},
+	reflect.TypeOf(sqlext.NullString{}):  {"type": "string", "nullable": true},
+	reflect.TypeOf(sqlext.NullTime{}):    {"type": "string", "example": "2006-01-02 15:04:05", "nullable": true},
+	reflect.TypeOf(sqlext.NullInt{}):     {"type": "integer", "format": "int32", "nullable": true},
+	reflect.TypeOf(sqlext.NullInt64{}):   {"type": "integer", "format": "int64", "nullable": true},
+	reflect.TypeOf(sqlext.NullFloat64{}): {"type": "number", "format": "double", "nullable": true},
+	reflect.TypeOf(sqlext.NullBool{}):    {"type": "boolean", "nullable": true},
+	reflect.TypeOf(sqlext.NullDecimal{}): {"type": "string", "format": "decimal", "nullable": true},
+	reflect.TypeOf(json.RawMessage{}):    {},
+	reflect.TypeOf(EmptyObj{}):           {"type": "object"},
+	reflect.TypeOf((*any)(nil)).Elem():   {},
+	reflect.TypeOf((*error)(nil)).Elem(): {"type": "string"},
+	reflect.TypeOf([]byte{}):             {"type": "string", "format": "byte"},
+	reflect.TypeOf(ReqVoTime{}):          {"type": "string", "example": "2006-01-02 15:04:05", "nullable": true},
+}
+
+// ReqVoEnum按元素类型描述,已注册的值作为enum
+type openApiEnumType interface {
+	reqVoEnumSchema() (reflect.Type, []any)
+}
+
+type openApiBuilder struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+// 类型对应的Schema,结构体写入components并返回$ref
+func (b *openApiBuilder) schemaOf(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if schema, ok := openApiTypeSchemas[t]; ok {
+		return copySchema(schema)
+	}
+	if enum, ok := reflect.Zero(t).Interface().(openApiEnumType); ok {
+		elemType, vals := enum.reqVoEnumSchema()
+		schema := b.schemaOf(elemType)
+		if len(vals) > 0 {
+			schema["enum"] = vals
+		}
+		return schema
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return map[string]any{"$ref": "#/components/schemas/" + b.structSchema(t)}
+	}
+	return map[string]any{}
+}
+
+func copySchema(schema map[string]any) map[string]any {
+	result := make(map[string]any, len(schema))
+	for k, v := range schema {
+		result[k] = v
+	}
+	return result
+}
+
+// 生成结构体的Schema并写入components,返回名称
+func (b *openApiBuilder) structSchema(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := b.schemaName(t)
+	b.names[t] = name
+	// 先占位,避免自引用的结构体无限递归
+	b.schemas[name] = map[string]any{"type": "object"}
+
+	properties := map[string]any{}
+	required := []string{}
+	for _, field := range openApiFields(t) {
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		schema := b.fieldSchema(field)
+		properties[jsonName] = schema
+		if openApiRequired(field) {
+			required = append(required, jsonName)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	b.schemas[name] = schema
+	return name
+}
+
+func (b *openApiBuilder) schemaName(t reflect.Type) string {
+	name := strings.Trim(openApiNameReg.ReplaceAllString(t.Name(), "_"), "_")
+	if name == "" {
+		name = "Anonymous"
+	}
+	if _, exist := b.schemas[name]; exist {
+		// 不同包的同名类型加上包名区分
+		pkgName := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.Trim(openApiNameReg.ReplaceAllString(pkgName, "_"), "_") + "_" + name
+	}
+	uniqueName := name
+	for i := 2; ; i++ {
+		if _, exist := b.schemas[uniqueName]; !exist {
+			return uniqueName
+		}
+		uniqueName = name + strconv.Itoa(i)
+	}
+}
+
+// 字段的Schema,zhdesc作为描述,binding规则作为约束
+func (b *openApiBuilder) fieldSchema(field reflect.StructField) map[string]any {
+	schema := b.schemaOf(field.Type)
+	_, isRef := schema["$ref"]
+	if isRef {
+		// $ref 不能与其他属性同级
+		schema = map[string]any{"allOf": []any{schema}}
+	}
+	if desc := field.Tag.Get("zhdesc"); desc != "" {
+		schema["description"] = desc
+	}
+	openApiBindingRules(schema, field)
+	if isRef && len(schema) == 1 {
+		return schema["allOf"].([]any)[0].(map[string]any)
+	}
+	return schema
+}
+
+// 结构体中需要描述的字段,展开匿名嵌入的结构体,忽略未导出、json:"-"、swaggerignore:"true"的字段
+func openApiFields(t reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" || field.Tag.Get("swaggerignore") == "true" {
+			continue
+		}
+		if field.Anonymous && jsonTag == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				if _, special := openApiTypeSchemas[fieldType]; !special {
+					fields = append(fields, openApiFields(fieldType)...)
+					continue
+				}
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// GET/DELETE请求的Query参数,参数名与gin的form绑定一致:form标签,没有时为字段名
+func (b *openApiBuilder) queryParams(t reflect.Type, pathParams []string) []any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	params := []any{}
+	for _, field := range openApiFields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if slices.Contains(pathParams, name) {
+			continue
+		}
+		schema := b.fieldSchema(field)
+		param := map[string]any{"name": name, "in": "query", "required": openApiRequired(field), "schema": schema}
+		if desc, ok := schema["description"]; ok {
+			param["description"] = desc
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func openApiRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "dive" {
+			return false
+		}
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// binding规则转换为Schema约束,dive之后的规则作用于元素,不再处理
+func openApiBindingRules(schema map[string]any, field reflect.StructField) {
+	fieldType := field.Type
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	schemaType, _ := schema["type"].(string)
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "dive" {
+			return
+		}
+		key, val, _ := strings.Cut(rule, "=")
+		switch key {
+		case "min", "max", "len", "gte", "lte", "gt", "lt":
+			num, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			setBoundRule(schema, schemaType, fieldType.Kind(), key, num)
+		case "oneof":
+			enums := []any{}
+			for _, item := range strings.Fields(val) {
+				if schemaType == "integer" || schemaType == "number" {
+					if num, err := strconv.ParseFloat(item, 64); err == nil {
+						enums = append(enums, openApiNum(num))
+						continue
+					}
+				}
+				enums = append(enums, item)
+			}
+			schema["enum"] = enums
+		case "email":
+			schema["format"] = "email"
+		case "url", "uri":
+			schema["format"] = "uri"
+		case "uuid":
+			schema["format"] = "uuid"
+		case "ipv4":
+			schema["format"] = "ipv4"
+		case "ipv6":
+			schema["format"] = "ipv6"
+		}
+	}
+}
+
+// 字符串约束长度,数组约束元素个数,数值约束大小
+func setBoundRule(schema map[string]any, schemaType string, kind reflect.Kind, key string, num float64) {
+	minKey, maxKey := "minimum", "maximum"
+	isLength := false
+	switch {
+	case kind == reflect.String:
+		minKey, maxKey, isLength = "minLength", "maxLength", true
+	case kind == reflect.Slice || kind == reflect.Array:
+		minKey, maxKey, isLength = "minItems", "maxItems", true
+	case kind == reflect.Map:
+		minKey, maxKey, isLength = "minProperties", "maxProperties", true
+	case schemaType != "integer" && schemaType != "number":
+		return
+	}
+	switch key {
+	case "min", "gte":
+		schema[minKey] = openApiNum(num)
+	case "max", "lte":
+		schema[maxKey] = openApiNum(num)
+	case "len":
+		schema[minKey] = openApiNum(num)
+		schema[maxKey] = openApiNum(num)
+	case "gt":
+		if isLength {
+			schema[minKey] = openApiNum(num + 1)
+		} else {
+			schema[minKey] = openApiNum(num)
+			schema["exclusiveMinimum"] = true
+		}
+	case "lt":
+		if isLength {
+			schema[maxKey] = openApiNum(num - 1)
+		} else {
+			schema[maxKey] = openApiNum(num)
+			schema["exclusiveMaximum"] = true
+		}
+	}
+}
+
+func openApiNum(num float64) any {
+	if num == float64(int64(num)) {
+		return int64(num)
+	}
+	return num
+}
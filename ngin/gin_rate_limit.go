@@ -0,0 +1,62 @@
+package ngin
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 限流器,rediscache.RedisRateLimiter 与 mencache.MemRateLimiter 均已实现
+type NRateLimiter interface {
+	// 判断key本次请求是否通过,未通过时返回需等待的时间
+	Allow(key string) (bool, time.Duration, error)
+}
+
+// 从请求中获取限流的Key
+type RateLimitKeyFunc func(ctx *gin.Context) string
+
+// 按客户端IP限流
+func RateLimitKeyByIp(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// 按User-Token限流,未携带Token时按客户端IP限流,需在HeaderSetHandlerFunc之后使用
+func RateLimitKeyByUserToken(ctx *gin.Context) string {
+	headerVo := GetHeaderVoFromCtx(ctx)
+	if headerVo.UserToken == "" {
+		return RateLimitKeyByIp(ctx)
+	}
+	return "token:" + headerVo.UserToken
+}
+
+// 按路由限流,所有客户端共享同一个限额
+func RateLimitKeyByRoute(ctx *gin.Context) string {
+	return "route:" + ctx.Request.Method + ":" + ctx.FullPath()
+}
+
+// 限流中间件,超过限额时返回429及Retry-After
+// 限流器异常时放行,避免缓存故障导致服务不可用
+func RateLimitHandlerFunc(limiter NRateLimiter, keyFunc RateLimitKeyFunc) gin.HandlerFunc {
+	slog.Debug("Add Middleware RateLimitHandlerFunc")
+	return func(ctx *gin.Context) {
+		key := keyFunc(ctx)
+		allowed, retryAfter, err := limiter.Allow(key)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("限流[%s]检查失败,本次放行:%v", key, err))
+			ctx.Next()
+			return
+		}
+		if !allowed {
+			retrySeconds := int64(math.Ceil(retryAfter.Seconds()))
+			ctx.Header("Retry-After", strconv.FormatInt(max(1, retrySeconds), 10))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, NewNoBaseResp(RespCode_Rate_Limit, "请求过于频繁，请稍后重试"))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -7,12 +7,17 @@ import (
 )
 
 const (
-	RespCode_OK           = 0
-	RespCode_Valid_Err    = 1000 // 验证错误
-	RespCode_RunTime_Err  = 2000 // 运行时异常
-	RespCode_RunTime_Err2 = 3000 // 捕获上游异常，转运行时异常
-	RespCode_UnLogin      = 9000 // 登录过期
-	RespCode_UnKnown_Err  = 9999 // 其他错误
+	RespCode_OK              = 0
+	RespCode_Valid_Err       = 1000 // 验证错误
+	RespCode_RunTime_Err     = 2000 // 运行时异常
+	RespCode_RunTime_Err2    = 3000 // 捕获上游异常，转运行时异常
+	RespCode_Sign_Err        = 4030 // 签名验证失败
+	RespCode_Endn_Err        = 4060 // 报文解密失败
+	RespCode_Idem_Processing = 4090 // 重复请求处理中
+	RespCode_Rate_Limit      = 4290 // 请求过于频繁
+	RespCode_UnLogin         = 9000 // 登录过期
+	RespCode_Forbidden       = 9001 // 无访问权限
+	RespCode_UnKnown_Err     = 9999 // 其他错误
 )
 
 // EmptyObj ...
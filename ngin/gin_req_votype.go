@@ -3,7 +3,14 @@ package ngin
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/niexqc/nlibs/ntools"
 )
 
 type ReqVoInt int
@@ -118,3 +125,291 @@ func (i *ReqVoFloat64) UnmarshalJSON(data []byte) error {
 func (i *ReqVoFloat64) Value() float64 {
 	return float64(*i)
 }
+
+// 去除首尾空白的字符串,也接受数字
+type ReqVoTrimString string
+
+func NewReqVoTrimString(val string) ReqVoTrimString {
+	return ReqVoTrimString(strings.TrimSpace(val))
+}
+
+func (i *ReqVoTrimString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*i = NewReqVoTrimString(s)
+		return nil
+	}
+	// 若前端传递的是数字类型，保留原始文本
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return fmt.Errorf("错误的字符串: %s", data)
+	}
+	*i = ReqVoTrimString(num)
+	return nil
+}
+
+// Query、Form绑定时同样去除空白
+func (i *ReqVoTrimString) UnmarshalParam(param string) error {
+	*i = NewReqVoTrimString(param)
+	return nil
+}
+
+func (i *ReqVoTrimString) Value() string {
+	return string(*i)
+}
+
+// ReqVoTime可接受的时间格式,按顺序尝试,带时区的格式按其时区解析,其余按本地时区
+var ReqVoTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102150405",
+	"20060102",
+}
+
+// 时间,接受ReqVoTimeLayouts中的格式或毫秒时间戳,空字符串与null为零值
+// 通过NValider校验时按time.Time处理,零值不满足required
+type ReqVoTime struct {
+	time.Time
+}
+
+func NewReqVoTime(val time.Time) ReqVoTime {
+	return ReqVoTime{Time: val}
+}
+
+func (i *ReqVoTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return i.UnmarshalParam(s)
+	}
+	// 若前端传递的是数字类型，按毫秒时间戳解析
+	var millis *int64
+	if err := json.Unmarshal(data, &millis); err != nil {
+		return fmt.Errorf("错误的时间: %s", data)
+	}
+	i.Time = time.Time{}
+	if millis != nil {
+		i.Time = time.UnixMilli(*millis)
+	}
+	return nil
+}
+
+func (i *ReqVoTime) UnmarshalParam(param string) error {
+	param = strings.TrimSpace(param)
+	if param == "" {
+		i.Time = time.Time{}
+		return nil
+	}
+	for _, layout := range ReqVoTimeLayouts {
+		if val, err := time.ParseInLocation(layout, param, time.Local); err == nil {
+			i.Time = val
+			return nil
+		}
+	}
+	if millis, err := strconv.ParseInt(param, 10, 64); err == nil {
+		i.Time = time.UnixMilli(millis)
+		return nil
+	}
+	return fmt.Errorf("错误的时间字符串: %s", param)
+}
+
+// 零值输出null,其他输出yyyy-MM-dd HH:mm:ss
+func (i ReqVoTime) MarshalJSON() ([]byte, error) {
+	if i.Time.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(ntools.Time2Str(i.Time))
+}
+
+func (i *ReqVoTime) Value() time.Time {
+	return i.Time
+}
+
+func registerReqVoFunc(validate *validator.Validate) {
+	validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(ReqVoTime).Time
+	}, ReqVoTime{})
+}
+
+// ReqVoIdList与ReqVoEnum的元素类型
+type ReqVoScalar interface {
+	~int | ~int32 | ~int64 | ~string
+}
+
+// ID列表,接受逗号分隔的字符串 "1,2,3" 或数组 [1,"2",3],空元素与零值被忽略
+// 通过NValider校验时为切片,如 binding:"required,gte=1,dive,gte=1"
+// Query、Form绑定时使用 ids=1&ids=2
+type ReqVoIdList[T ReqVoScalar] []T
+
+func NewReqVoIdList[T ReqVoScalar](vals ...T) ReqVoIdList[T] {
+	return ReqVoIdList[T](vals)
+}
+
+func (i *ReqVoIdList[T]) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*i = nil
+		if s == nil {
+			return nil
+		}
+		for _, item := range strings.Split(*s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			val, err := parseReqVoScalar[T](item)
+			if err != nil {
+				return err
+			}
+			if !reflect.ValueOf(val).IsZero() {
+				*i = append(*i, val)
+			}
+		}
+		return nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("错误的ID列表: %s", data)
+	}
+	list := make(ReqVoIdList[T], 0, len(items))
+	for _, item := range items {
+		val, err := unmarshalReqVoScalar[T](item)
+		if err != nil {
+			return err
+		}
+		if !reflect.ValueOf(val).IsZero() {
+			list = append(list, val)
+		}
+	}
+	*i = list
+	return nil
+}
+
+func (i *ReqVoIdList[T]) Value() []T {
+	return []T(*i)
+}
+
+// 枚举,值需通过RegisterReqVoEnum注册,配合enum规则校验,如 binding:"required,enum"
+// 接受字符串或数字,T建议使用自定义类型以区分不同枚举
+type ReqVoEnum[T ReqVoScalar] struct {
+	val T
+}
+
+func NewReqVoEnum[T ReqVoScalar](val T) ReqVoEnum[T] {
+	return ReqVoEnum[T]{val: val}
+}
+
+func (i *ReqVoEnum[T]) UnmarshalJSON(data []byte) error {
+	val, err := unmarshalReqVoScalar[T](data)
+	if err != nil {
+		return err
+	}
+	i.val = val
+	return nil
+}
+
+func (i *ReqVoEnum[T]) UnmarshalParam(param string) error {
+	val, err := parseReqVoScalar[T](strings.TrimSpace(param))
+	if err != nil {
+		return err
+	}
+	i.val = val
+	return nil
+}
+
+func (i ReqVoEnum[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.val)
+}
+
+func (i *ReqVoEnum[T]) Value() T {
+	return i.val
+}
+
+// 元素类型及已注册的枚举值,用于生成接口文档
+func (i ReqVoEnum[T]) reqVoEnumSchema() (reflect.Type, []any) {
+	elemType := reflect.TypeFor[T]()
+	if enumSet, ok := reqVoEnums.Load(elemType); ok {
+		return elemType, enumSet.(*reqVoEnumSet).vals
+	}
+	return elemType, nil
+}
+
+// 已注册的枚举值,key为枚举的元素类型
+var reqVoEnums sync.Map
+
+type reqVoEnumSet struct {
+	vals []any
+	set  map[any]bool
+}
+
+// 注册T的枚举值,同一类型重复注册时覆盖
+// 校验时ReqVoEnum[T]按T处理,零值不满足required
+func RegisterReqVoEnum[T ReqVoScalar](nValider *NValider, vals ...T) {
+	enumSet := &reqVoEnumSet{set: map[any]bool{}}
+	for _, v := range vals {
+		enumSet.vals = append(enumSet.vals, v)
+		enumSet.set[v] = true
+	}
+	reqVoEnums.Store(reflect.TypeFor[T](), enumSet)
+	nValider.Validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(ReqVoEnum[T]).val
+	}, ReqVoEnum[T]{})
+}
+
+// enum规则:值在注册的枚举值中
+func isReqVoEnum(fl validator.FieldLevel) bool {
+	enumSet, ok := reqVoEnums.Load(fl.Field().Type())
+	return ok && fl.Field().CanInterface() && enumSet.(*reqVoEnumSet).set[fl.Field().Interface()]
+}
+
+// enum规则翻译的参数,注册的枚举值以空格分隔
+func reqVoEnumParam(fe validator.FieldError) string {
+	enumSet, ok := reqVoEnums.Load(fe.Type())
+	if !ok {
+		return ""
+	}
+	vals := make([]string, 0, len(enumSet.(*reqVoEnumSet).vals))
+	for _, v := range enumSet.(*reqVoEnumSet).vals {
+		vals = append(vals, fmt.Sprint(v))
+	}
+	return strings.Join(vals, " ")
+}
+
+// 字符串按T的类型转换
+func parseReqVoScalar[T ReqVoScalar](s string) (T, error) {
+	var val T
+	rv := reflect.ValueOf(&val).Elem()
+	if rv.Kind() == reflect.String {
+		rv.SetString(s)
+		return val, nil
+	}
+	num, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+	if err != nil {
+		return val, fmt.Errorf("错误的%s字符串: %s", rv.Kind(), s)
+	}
+	rv.SetInt(num)
+	return val, nil
+}
+
+// 接受字符串或数字,null为零值
+func unmarshalReqVoScalar[T ReqVoScalar](data []byte) (T, error) {
+	var val T
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s = strings.TrimSpace(s); s == "" {
+			return val, nil
+		}
+		return parseReqVoScalar[T](s)
+	}
+	// 若前端传递的是数字类型，按原始文本转换
+	var num *json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return val, fmt.Errorf("错误的值: %s", data)
+	}
+	if num == nil {
+		return val, nil
+	}
+	return parseReqVoScalar[T](num.String())
+}
@@ -0,0 +1,222 @@
+package ngin
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+var defaultCorsMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// 跨域处理,需使用nGin.Use注册,未匹配路由的预检请求也会经过
+// 来源不允许时:预检请求返回403,普通请求不设置跨域响应头由浏览器拦截
+// 预检请求直接返回204,不再执行后续处理
+// 允许全部来源时不能允许凭证,否则任意网站都可携带凭证跨域读取
+func CorsHandlerFunc(conf *nyaml.YamlConfCors) gin.HandlerFunc {
+	slog.Debug("Add Middleware CorsHandlerFunc")
+	allowAll := slices.Contains(conf.AllowOrigins, "*")
+	if allowAll && conf.AllowCredentials {
+		panic(nerror.NewRunTimeError("CORS允许全部来源时不能开启allowCredentials,请配置具体的来源"))
+	}
+	methods := conf.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCorsMethods
+	}
+	allowMethods := strings.ToUpper(strings.Join(methods, ", "))
+	allowHeaders := strings.Join(conf.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(conf.ExposeHeaders, ", ")
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+		header := ctx.Writer.Header()
+		header.Add("Vary", "Origin")
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != ""
+		if !allowAll && !corsOriginAllowed(origin, conf.AllowOrigins) {
+			if preflight {
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			ctx.Next()
+			return
+		}
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if conf.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			ctx.Next()
+			return
+		}
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if reqHeaders := ctx.GetHeader("Access-Control-Request-Headers"); reqHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", reqHeaders)
+		}
+		if conf.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(conf.MaxAge))
+		}
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// 支持精确匹配与通配子域名 https://*.example.com
+func corsOriginAllowed(origin string, allowOrigins []string) bool {
+	for _, allow := range allowOrigins {
+		if strings.EqualFold(allow, origin) {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(allow, "*")
+		if ok && len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// 安全响应头
+// 始终设置X-Content-Type-Options: nosniff,X-Frame-Options与Referrer-Policy为空时使用默认值
+// HSTS仅在HTTPS请求(含X-Forwarded-Proto: https)时设置
+func SecurityHeadersHandlerFunc(conf *nyaml.YamlConfSecurityHeaders) gin.HandlerFunc {
+	slog.Debug("Add Middleware SecurityHeadersHandlerFunc")
+	frameOptions := conf.FrameOptions
+	if frameOptions == "" {
+		frameOptions = "DENY"
+	}
+	referrerPolicy := conf.ReferrerPolicy
+	if referrerPolicy == "" {
+		referrerPolicy = "strict-origin-when-cross-origin"
+	}
+	hsts := ""
+	if conf.HstsMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", conf.HstsMaxAge)
+		if conf.HstsIncludeSubDomains {
+			hsts += "; includeSubDomains"
+		}
+		if conf.HstsPreload {
+			hsts += "; preload"
+		}
+	}
+	return func(ctx *gin.Context) {
+		header := ctx.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", frameOptions)
+		header.Set("Referrer-Policy", referrerPolicy)
+		if conf.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", conf.ContentSecurityPolicy)
+		}
+		if hsts != "" && (ctx.Request.TLS != nil || strings.EqualFold(ctx.GetHeader("X-Forwarded-Proto"), "https")) {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		ctx.Next()
+	}
+}
+
+// IP访问控制,先检查禁止列表再检查允许列表,不通过时返回RespCode_Forbidden
+// 仅信任来自TrustedProxies的X-Forwarded-For/X-Real-Ip,未配置时使用连接的IP
+func IpAclHandlerFunc(conf *nyaml.YamlConfIpAcl) gin.HandlerFunc {
+	slog.Debug("Add Middleware IpAclHandlerFunc")
+	allows := parseIpPrefixes(conf.AllowCidrs)
+	denys := parseIpPrefixes(conf.DenyCidrs)
+	proxies := parseIpPrefixes(conf.TrustedProxies)
+	return func(ctx *gin.Context) {
+		clientIp := clientIpByTrustedProxies(ctx.Request, proxies)
+		addr, err := netip.ParseAddr(clientIp)
+		if err != nil || ipInPrefixes(addr, denys) || (len(allows) > 0 && !ipInPrefixes(addr, allows)) {
+			slog.Warn(fmt.Sprintf("IP[%s]禁止访问:%s", clientIp, ctx.Request.URL.Path))
+			ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Forbidden, "IP禁止访问"))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// 设置可信代理,ctx.ClientIP()仅信任来自可信代理的X-Forwarded-For/X-Real-Ip
+// proxies为nil时不信任任何代理,ctx.ClientIP()直接使用连接的IP
+func (nGin *NGin) SetTrustedProxies(proxies []string) error {
+	return nGin.GinEngine.SetTrustedProxies(proxies)
+}
+
+// 解析IP或CIDR,格式错误时panic
+func parseIpPrefixes(cidrs []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				panic(nerror.NewRunTimeErrorWithError(fmt.Sprintf("IP[%s]格式错误", cidr), err))
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			panic(nerror.NewRunTimeErrorWithError(fmt.Sprintf("CIDR[%s]格式错误", cidr), err))
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+func ipInPrefixes(addr netip.Addr, prefixes []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// 连接来自可信代理时,从右向左取X-Forwarded-For中第一个非可信代理的IP
+func clientIpByTrustedProxies(req *http.Request, proxies []netip.Prefix) string {
+	remoteIp, _, err := net.SplitHostPort(strings.TrimSpace(req.RemoteAddr))
+	if err != nil {
+		remoteIp = strings.TrimSpace(req.RemoteAddr)
+	}
+	remote, err := netip.ParseAddr(remoteIp)
+	if err != nil || !ipInPrefixes(remote, proxies) {
+		return remoteIp
+	}
+	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		items := strings.Split(forwarded, ",")
+		for i := len(items) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(items[i])
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				return ip
+			}
+			if i == 0 || !ipInPrefixes(addr, proxies) {
+				return ip
+			}
+		}
+	}
+	if realIp := strings.TrimSpace(req.Header.Get("X-Real-Ip")); realIp != "" {
+		return realIp
+	}
+	return remoteIp
+}
@@ -0,0 +1,155 @@
+package ngin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/njson"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+// SSE事件
+type NSseEvent struct {
+	// 事件ID,客户端重连时通过Last-Event-ID带回
+	Id string
+	// 事件名称,为空时为message
+	Event string
+	// 字符串或[]byte原样发送,其他类型转为JSON
+	Data any
+	// 客户端重连间隔-毫秒,0为不设置
+	Retry int
+}
+
+// SSE输出流
+type NSseStream struct {
+	ctx       *gin.Context
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+	// 请求的跟踪ID
+	TraceId string
+}
+
+// 开启SSE输出流,客户端断开或调用Close后Done关闭
+// heartbeat 大于0时定时发送注释行,保持连接并及时发现客户端断开
+// 处理函数返回后连接关闭,返回前需调用Close
+func NewSseStream(ctx *gin.Context, heartbeat time.Duration) *NSseStream {
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭Nginx的响应缓冲
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	stream := &NSseStream{ctx: ctx, done: make(chan struct{}), TraceId: ntools.SlogGetTraceId()}
+	reqDone := ctx.Request.Context().Done()
+	go func() {
+		select {
+		case <-reqDone:
+			stream.Close()
+		case <-stream.done:
+		}
+	}()
+	if heartbeat > 0 {
+		go stream.heartbeat(heartbeat)
+	}
+	return stream
+}
+
+func (s *NSseStream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.write(": ping\n\n"); err != nil {
+				s.Close()
+				return
+			}
+		}
+	}
+}
+
+// 客户端断开或已关闭时关闭
+func (s *NSseStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// 关闭输出流,返回后不会再写出,处理函数应 defer stream.Close()
+func (s *NSseStream) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// 发送message事件
+func (s *NSseStream) Send(data any) error {
+	return s.SendEvent(NSseEvent{Data: data})
+}
+
+// 发送事件,已关闭时返回错误
+func (s *NSseStream) SendEvent(event NSseEvent) error {
+	var data string
+	switch v := event.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		jsonStr, err := njson.Obj2JsonStr(v)
+		if err != nil {
+			return err
+		}
+		data = jsonStr
+	}
+	sb := &strings.Builder{}
+	if event.Id != "" {
+		fmt.Fprintf(sb, "id: %s\n", sseLine(event.Id))
+	}
+	if event.Event != "" {
+		fmt.Fprintf(sb, "event: %s\n", sseLine(event.Event))
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(sb, "retry: %d\n", event.Retry)
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		fmt.Fprintf(sb, "data: %s\n", line)
+	}
+	sb.WriteString("\n")
+	if err := s.write(sb.String()); err != nil {
+		s.Close()
+		return err
+	}
+	return nil
+}
+
+func (s *NSseStream) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return nerror.NewRunTimeError("SSE连接已关闭")
+	default:
+	}
+	if _, err := s.ctx.Writer.WriteString(text); err != nil {
+		return err
+	}
+	s.ctx.Writer.Flush()
+	return nil
+}
+
+// id与event不能换行
+func sseLine(val string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(val)
+}
@@ -0,0 +1,222 @@
+package ngin
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/nalioss"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+// 上传结果
+type NUploadResult struct {
+	FileName string `json:"fileName" zhdesc:"原文件名"`
+	ObjKey   string `json:"objKey" zhdesc:"对象键"`
+	Url      string `json:"url" zhdesc:"访问地址"`
+	Size     int64  `json:"size" zhdesc:"文件大小"`
+	Ext      string `json:"ext" zhdesc:"扩展名"`
+	MimeType string `json:"mimeType" zhdesc:"按内容识别的文件类型"`
+	Md5      string `json:"md5" zhdesc:"MD5小写Hex"`
+	Sm3      string `json:"sm3" zhdesc:"SM3小写Hex"`
+}
+
+// 上传文件的存储
+type NUploadStore interface {
+	// 保存校验通过的临时文件,返回访问地址
+	Store(objKey, tmpFile string) (string, error)
+}
+
+// 文件上传
+type NUploader struct {
+	conf  *nyaml.YamlConfUpload
+	store NUploadStore
+}
+
+func NewNUploader(conf *nyaml.YamlConfUpload, store NUploadStore) *NUploader {
+	return &NUploader{conf: conf, store: store}
+}
+
+// 上传单个文件的处理函数,成功时返回NewOkBaseResp(*NUploadResult)
+func (u *NUploader) HandlerFunc(fieldName string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		result, err := u.SaveFormFile(ctx, fieldName)
+		if err != nil {
+			AbortWithErr(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, NewOkBaseResp(result))
+	}
+}
+
+// 校验并保存表单中的文件
+// 先检查大小与扩展名,再按前512字节识别文件类型,复制到临时文件时计算MD5与SM3,最后交给NUploadStore保存
+// 对象键为 年/月/日/UUID+扩展名
+func (u *NUploader) SaveFormFile(ctx *gin.Context, fieldName string) (*NUploadResult, error) {
+	fileHeader, err := ctx.FormFile(fieldName)
+	if err != nil {
+		return nil, uploadValidErr(fmt.Sprintf("未上传文件[%s]", fieldName))
+	}
+	maxSize := u.conf.MaxSizeMb << 20
+	if maxSize > 0 && fileHeader.Size > maxSize {
+		return nil, uploadValidErr(fmt.Sprintf("文件大小不能超过%dMB", u.conf.MaxSizeMb))
+	}
+	fileName := filepath.Base(strings.ReplaceAll(fileHeader.Filename, "\\", "/"))
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if len(u.conf.AllowExts) > 0 && !slices.ContainsFunc(u.conf.AllowExts, func(allow string) bool { return strings.EqualFold(allow, ext) }) {
+		return nil, uploadValidErr(fmt.Sprintf("不支持的文件扩展名[%s]", ext))
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return nil, nerror.NewRunTimeErrorWithError("读取上传文件失败", err)
+	}
+	defer src.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, nerror.NewRunTimeErrorWithError("读取上传文件失败", err)
+	}
+	head = head[:n]
+	mimeType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if len(u.conf.AllowMimeTypes) > 0 && !mimeTypeAllowed(mimeType, u.conf.AllowMimeTypes) {
+		return nil, uploadValidErr(fmt.Sprintf("不支持的文件类型[%s]", mimeType))
+	}
+
+	tmpFile, err := os.CreateTemp("", "nupload-*"+ext)
+	if err != nil {
+		return nil, nerror.NewRunTimeErrorWithError("创建临时文件失败", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	md5Hash, sm3Hash := md5.New(), sm3.New()
+	reader := io.MultiReader(bytes.NewReader(head), src)
+	if maxSize > 0 {
+		reader = io.LimitReader(reader, maxSize+1)
+	}
+	size, err := io.Copy(io.MultiWriter(tmpFile, md5Hash, sm3Hash), reader)
+	tmpFile.Close()
+	if err != nil {
+		return nil, nerror.NewRunTimeErrorWithError("保存上传文件失败", err)
+	}
+	if maxSize > 0 && size > maxSize {
+		return nil, uploadValidErr(fmt.Sprintf("文件大小不能超过%dMB", u.conf.MaxSizeMb))
+	}
+
+	objKey := time.Now().Format("2006/01/02/") + ntools.UUIDStr(false) + ext
+	url, err := u.store.Store(objKey, tmpFile.Name())
+	if err != nil {
+		return nil, nerror.NewRunTimeErrorWithError("保存上传文件失败", err)
+	}
+	return &NUploadResult{
+		FileName: fileName,
+		ObjKey:   objKey,
+		Url:      url,
+		Size:     size,
+		Ext:      ext,
+		MimeType: mimeType,
+		Md5:      hex.EncodeToString(md5Hash.Sum(nil)),
+		Sm3:      hex.EncodeToString(sm3Hash.Sum(nil)),
+	}, nil
+}
+
+func uploadValidErr(msg string) error {
+	return &NiexqValidErr{ErrDescList: []string{msg}}
+}
+
+// 支持 image/* 的写法
+func mimeTypeAllowed(mimeType string, allows []string) bool {
+	for _, allow := range allows {
+		if strings.EqualFold(allow, mimeType) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allow, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// 保存到本地目录
+type localUploadStore struct {
+	dir       string
+	urlPrefix string
+}
+
+// 保存到conf.LocalDir,返回conf.UrlPrefix+对象键
+func NewLocalUploadStore(conf *nyaml.YamlConfUpload) NUploadStore {
+	if conf.LocalDir == "" {
+		panic(nerror.NewRunTimeError("未配置上传文件的localDir"))
+	}
+	return &localUploadStore{dir: conf.LocalDir, urlPrefix: conf.UrlPrefix}
+}
+
+func (s *localUploadStore) Store(objKey, tmpFile string) (string, error) {
+	target := filepath.Join(s.dir, filepath.FromSlash(objKey))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	// 临时目录与目标目录不在同一个文件系统时Rename失败,改为复制
+	if err := os.Rename(tmpFile, target); err != nil {
+		if err := copyFile(tmpFile, target); err != nil {
+			return "", err
+		}
+	}
+	return s.urlPrefix + objKey, nil
+}
+
+func copyFile(srcFile, dstFile string) error {
+	src, err := os.Open(srcFile)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dstFile, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dstFile)
+		return err
+	}
+	return dst.Close()
+}
+
+// 保存到阿里云OSS
+type ossUploadStore struct {
+	client    *nalioss.NAliOssClient
+	chunkSize int64
+	urlPrefix string
+}
+
+// 保存到OSS,对象键前加上OssPrefix,超过conf.OssChunkSizeMb时分片上传
+func NewOssUploadStore(client *nalioss.NAliOssClient, conf *nyaml.YamlConfUpload) NUploadStore {
+	chunkSizeMb := conf.OssChunkSizeMb
+	if chunkSizeMb <= 0 {
+		chunkSizeMb = 10
+	}
+	return &ossUploadStore{client: client, chunkSize: chunkSizeMb << 20, urlPrefix: conf.UrlPrefix}
+}
+
+func (s *ossUploadStore) Store(objKey, tmpFile string) (string, error) {
+	ossKey := path.Join(s.client.Cnf.OssPrefix, objKey)
+	if err := s.client.MultipartUpload(ossKey, tmpFile, s.chunkSize); err != nil {
+		slog.Error(fmt.Sprintf("上传文件到OSS失败[%s]:%v", ossKey, err))
+		return "", err
+	}
+	return s.urlPrefix + ossKey, nil
+}
@@ -0,0 +1,182 @@
+package ngin
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+)
+
+// 请求头中指定校验信息的语言,优先于Accept-Language
+const LocaleHeader = "Locale"
+
+// 校验信息的语言
+type NValiderLocale struct {
+	Trans ut.Translator
+	// 字段名显示为zhdesc[json],否则只显示json名
+	FieldWithDesc bool
+	// 跨字段规则参数的连接词:字段与值之间、多个条件之间、多个字段之间
+	PairSep string
+	CondSep string
+	ListSep string
+}
+
+// 内置规则与跨字段规则的英文翻译
+var nEnRuleTrans = map[string]string{
+	"idcard":   "{0} must be a valid ID card number",
+	"mobile":   "{0} must be a valid mobile number",
+	"uscc":     "{0} must be a valid unified social credit code",
+	"postcode": "{0} must be a valid postal code",
+	"plate":    "{0} must be a valid plate number",
+	"enum":     "{0} must be one of [{1}]",
+
+	"eqfield":    "{0} must be equal to {1}",
+	"nefield":    "{0} cannot be equal to {1}",
+	"gtfield":    "{0} must be greater than {1}",
+	"gtefield":   "{0} must be greater than or equal to {1}",
+	"ltfield":    "{0} must be less than {1}",
+	"ltefield":   "{0} must be less than or equal to {1}",
+	"eqcsfield":  "{0} must be equal to {1}",
+	"necsfield":  "{0} cannot be equal to {1}",
+	"gtcsfield":  "{0} must be greater than {1}",
+	"gtecsfield": "{0} must be greater than or equal to {1}",
+	"ltcsfield":  "{0} must be less than {1}",
+	"ltecsfield": "{0} must be less than or equal to {1}",
+
+	"required_if":          "{0} is required when {1}",
+	"required_unless":      "{0} is required unless {1}",
+	"required_with":        "{0} is required when any of {1} is present",
+	"required_with_all":    "{0} is required when all of {1} are present",
+	"required_without":     "{0} is required when any of {1} is missing",
+	"required_without_all": "{0} is required when all of {1} are missing",
+	"excluded_if":          "{0} must be empty when {1}",
+	"excluded_unless":      "{0} must be empty unless {1}",
+	"excluded_with":        "{0} must be empty when any of {1} is present",
+	"excluded_with_all":    "{0} must be empty when all of {1} are present",
+	"excluded_without":     "{0} must be empty when any of {1} is missing",
+	"excluded_without_all": "{0} must be empty when all of {1} are missing",
+}
+
+func registerEnLocale(nvld *NValider) {
+	localEn := en.New()
+	enTrans, _ := ut.New(localEn, localEn).GetTranslator("en")
+	en_translations.RegisterDefaultTranslations(nvld.Validate, enTrans)
+	nvld.AddLocale("en", &NValiderLocale{Trans: enTrans, PairSep: " is ", CondSep: " and ", ListSep: ", "}, nEnRuleTrans)
+}
+
+// 添加语言,需在处理请求前调用
+// loc.Trans需已注册默认翻译,如 ja_translations.RegisterDefaultTranslations(nvld.Validate, jaTrans)
+// ruleTrans为内置规则、跨字段规则及自定义规则的翻译,会覆盖默认翻译
+func (nvld *NValider) AddLocale(locale string, loc *NValiderLocale, ruleTrans map[string]string) {
+	for tag, tpl := range ruleTrans {
+		registerTrans(nvld.Validate, loc, tag, tpl)
+	}
+	nvld.locales[strings.ToLower(locale)] = loc
+}
+
+// 为已添加的语言注册规则翻译,{0}为字段名,{1}为规则参数,{0}需在{1}之前
+func (nvld *NValider) RegisterRuleTrans(locale, tag, tpl string) {
+	if loc, ok := nvld.locales[strings.ToLower(locale)]; ok {
+		registerTrans(nvld.Validate, loc, tag, tpl)
+	}
+}
+
+// 按优先顺序匹配已添加的语言,如 zh-CN 可匹配 zh,都未匹配时返回DefaultLocale
+func (nvld *NValider) MatchLocale(langs ...string) string {
+	for _, lang := range langs {
+		lang = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "_", "-")
+		for lang != "" {
+			if _, ok := nvld.locales[lang]; ok {
+				return lang
+			}
+			idx := strings.LastIndex(lang, "-")
+			if idx < 0 {
+				break
+			}
+			lang = lang[:idx]
+		}
+	}
+	return nvld.DefaultLocale
+}
+
+// 请求的语言,依次从LocaleHeader与Accept-Language中匹配
+func (nvld *NValider) CtxLocale(ctx *gin.Context) string {
+	langs := []string{ctx.GetHeader(LocaleHeader)}
+	for _, v := range strings.Split(ctx.GetHeader("Accept-Language"), ",") {
+		lang, _, _ := strings.Cut(v, ";")
+		langs = append(langs, lang)
+	}
+	return nvld.MatchLocale(langs...)
+}
+
+// 按请求的语言翻译校验错误
+func (nvld *NValider) TransCtxErr(ctx *gin.Context, obj any, err error) error {
+	return nvld.TransErr(nvld.CtxLocale(ctx), obj, err)
+}
+
+// 按locale翻译校验错误,未添加的语言使用DefaultLocale
+// obj为校验对象,可为nil,用于解析跨字段规则引用的字段及非中文时的json名
+func (nvld *NValider) TransErr(locale string, obj any, err error) error {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	loc, ok := nvld.locales[strings.ToLower(locale)]
+	if !ok {
+		loc = nvld.locales[nvld.DefaultLocale]
+	}
+	var rootType reflect.Type
+	if obj != nil {
+		rootType = reflect.TypeOf(obj)
+	}
+	validErr := &NiexqValidErr{}
+	// 遍历每个错误并翻译
+	for _, e := range validationErrors {
+		field := nvld.localeField(loc, rootType, e)
+		desc, ok := nvld.transCrossField(loc, rootType, e, field)
+		if !ok {
+			desc = e.Translate(loc.Trans)
+			if field != e.Field() {
+				desc = strings.ReplaceAll(desc, e.Field(), field)
+			}
+		}
+		validErr.ErrDescList = append(validErr.ErrDescList, desc+";")
+	}
+	return validErr
+}
+
+// zhdesc[json][下标] 形式的字段名
+var descFieldReg = regexp.MustCompile(`^.+\[([A-Za-z_][\w.-]*)\]((?:\[[^\]]*\])*)$`)
+
+// 当前语言下的字段名
+func (nvld *NValider) localeField(loc *NValiderLocale, rootType reflect.Type, fe validator.FieldError) string {
+	if loc.FieldWithDesc {
+		return fe.Field()
+	}
+	if parent := nvld.parentType(rootType, fe); parent != nil {
+		name, index, hasIndex := strings.Cut(fe.StructField(), "[")
+		if fld, ok := parent.FieldByName(name); ok {
+			if hasIndex {
+				return nvld.plainFieldName(fld) + "[" + index
+			}
+			return nvld.plainFieldName(fld)
+		}
+	}
+	if m := descFieldReg.FindStringSubmatch(fe.Field()); m != nil {
+		return m[1] + m[2]
+	}
+	return fe.Field()
+}
+
+// json名,未设置时为字段名
+func (nvld *NValider) plainFieldName(fld reflect.StructField) string {
+	if tagJson, _, _ := strings.Cut(fld.Tag.Get(nvld.tagJsonName), ","); tagJson != "" {
+		return tagJson
+	}
+	return fld.Name
+}
@@ -0,0 +1,279 @@
+package ngin
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	mobileReg   = regexp.MustCompile(`^(?:\+?86)?1[3-9]\d{9}$`)
+	postcodeReg = regexp.MustCompile(`^\d{6}$`)
+	// 普通车牌与新能源车牌(小型车第3位为D/F,大型车第8位为D/F)
+	plateReg = regexp.MustCompile(`^[京津沪渝冀豫云辽黑湘皖鲁新苏浙赣鄂桂甘晋蒙陕吉闽贵粤青藏川宁琼][A-HJ-NP-Z](?:[A-HJ-NP-Z0-9]{4}[A-HJ-NP-Z0-9挂学警港澳]|[DF][A-HJ-NP-Z0-9]\d{4}|\d{5}[DF])$`)
+)
+
+var idCardWeights = [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+const idCardCheckCodes = "10X98765432"
+
+var usccWeights = [17]int{1, 3, 9, 27, 19, 26, 16, 17, 20, 29, 25, 13, 8, 24, 10, 30, 28}
+
+const usccChars = "0123456789ABCDEFGHJKLMNPQRTUWXY"
+
+// 18位居民身份证号,校验出生日期与GB 11643校验码,末位x不区分大小写
+func IsIdCard(s string) bool {
+	if len(s) != 18 || s[0] == '0' {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		sum += int(s[i]-'0') * idCardWeights[i]
+	}
+	if idCardCheckCodes[sum%11] != strings.ToUpper(s[17:])[0] {
+		return false
+	}
+	birthday, err := time.ParseInLocation("20060102", s[6:14], time.Local)
+	return err == nil && birthday.Year() >= 1900 && birthday.Before(time.Now())
+}
+
+// 大陆手机号,允许86或+86前缀
+func IsMobile(s string) bool {
+	return mobileReg.MatchString(s)
+}
+
+// 18位统一社会信用代码,校验GB 32100校验码
+func IsUscc(s string) bool {
+	if len(s) != 18 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		idx := strings.IndexByte(usccChars, s[i])
+		if idx < 0 {
+			return false
+		}
+		sum += idx * usccWeights[i]
+	}
+	return usccChars[(31-sum%31)%31] == s[17]
+}
+
+// 6位邮政编码
+func IsPostcode(s string) bool {
+	return postcodeReg.MatchString(s)
+}
+
+// 大陆车牌号,含新能源车牌
+func IsPlateNo(s string) bool {
+	return plateReg.MatchString(s)
+}
+
+// 内置的字符串规则
+var nStrRules = map[string]func(string) bool{
+	"idcard":   IsIdCard,
+	"mobile":   IsMobile,
+	"uscc":     IsUscc,
+	"postcode": IsPostcode,
+	"plate":    IsPlateNo,
+}
+
+// 内置规则与跨字段规则的中文翻译,{0}为字段名,{1}为规则参数,{0}需在{1}之前
+var nZhRuleTrans = map[string]string{
+	"idcard":   "{0}必须是有效的身份证号",
+	"mobile":   "{0}必须是有效的手机号",
+	"uscc":     "{0}必须是有效的统一社会信用代码",
+	"postcode": "{0}必须是有效的邮政编码",
+	"plate":    "{0}必须是有效的车牌号",
+	"enum":     "{0}必须是[{1}]中的一个",
+
+	"eqfield":    "{0}必须等于{1}",
+	"nefield":    "{0}不能等于{1}",
+	"gtfield":    "{0}必须大于{1}",
+	"gtefield":   "{0}必须大于或等于{1}",
+	"ltfield":    "{0}必须小于{1}",
+	"ltefield":   "{0}必须小于或等于{1}",
+	"eqcsfield":  "{0}必须等于{1}",
+	"necsfield":  "{0}不能等于{1}",
+	"gtcsfield":  "{0}必须大于{1}",
+	"gtecsfield": "{0}必须大于或等于{1}",
+	"ltcsfield":  "{0}必须小于{1}",
+	"ltecsfield": "{0}必须小于或等于{1}",
+
+	"required_if":          "{0}在{1}时为必填字段",
+	"required_unless":      "{0}在{1}不成立时为必填字段",
+	"required_with":        "{0}在{1}任一有值时为必填字段",
+	"required_with_all":    "{0}在{1}均有值时为必填字段",
+	"required_without":     "{0}在{1}任一为空时为必填字段",
+	"required_without_all": "{0}在{1}均为空时为必填字段",
+	"excluded_if":          "{0}在{1}时为禁填字段",
+	"excluded_unless":      "{0}在{1}不成立时为禁填字段",
+	"excluded_with":        "{0}在{1}任一有值时为禁填字段",
+	"excluded_with_all":    "{0}在{1}均有值时为禁填字段",
+	"excluded_without":     "{0}在{1}任一为空时为禁填字段",
+	"excluded_without_all": "{0}在{1}均为空时为禁填字段",
+}
+
+const (
+	// 参数为字段名列表,如 required_with=Phone Email
+	crossParamFields = iota + 1
+	// 参数为字段名与值成对出现,如 required_if=Type 1 Status 2
+	crossParamPairs
+)
+
+// 参数中引用了其他字段的规则,翻译时将字段名替换为zhdesc[json]
+var crossFieldTags = map[string]int{
+	"eqfield": crossParamFields, "nefield": crossParamFields, "gtfield": crossParamFields, "gtefield": crossParamFields,
+	"ltfield": crossParamFields, "ltefield": crossParamFields, "eqcsfield": crossParamFields, "necsfield": crossParamFields,
+	"gtcsfield": crossParamFields, "gtecsfield": crossParamFields, "ltcsfield": crossParamFields, "ltecsfield": crossParamFields,
+	"required_with": crossParamFields, "required_with_all": crossParamFields, "required_without": crossParamFields, "required_without_all": crossParamFields,
+	"excluded_with": crossParamFields, "excluded_with_all": crossParamFields, "excluded_without": crossParamFields, "excluded_without_all": crossParamFields,
+	"required_if": crossParamPairs, "required_unless": crossParamPairs, "excluded_if": crossParamPairs, "excluded_unless": crossParamPairs,
+}
+
+func registerNRules(validate *validator.Validate) {
+	for tag, fn := range nStrRules {
+		_ = validate.RegisterValidation(tag, strRuleFunc(fn))
+	}
+	_ = validate.RegisterValidation("enum", isReqVoEnum)
+}
+
+// 添加自定义规则及其中文翻译,zhTpl中{0}为字段名,{1}为规则参数,{0}需在{1}之前
+func (nvld *NValider) RegisterRule(tag string, fn validator.Func, zhTpl string) error {
+	if err := nvld.Validate.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	registerTrans(nvld.Validate, nvld.locales["zh"], tag, zhTpl)
+	return nil
+}
+
+// 添加自定义字符串规则,非字符串字段校验不通过
+func (nvld *NValider) RegisterStrRule(tag string, fn func(string) bool, zhTpl string) error {
+	return nvld.RegisterRule(tag, strRuleFunc(fn), zhTpl)
+}
+
+func strRuleFunc(fn func(string) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return fl.Field().Kind() == reflect.String && fn(fl.Field().String())
+	}
+}
+
+// 未能解析到结构体时,跨字段规则的参数保持原字段名
+func registerTrans(validate *validator.Validate, loc *NValiderLocale, tag, tpl string) {
+	_ = validate.RegisterTranslation(tag, loc.Trans, func(ut ut.Translator) error {
+		return ut.Add(tag, tpl, true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		param := fe.Param()
+		if tag == "enum" {
+			param = reqVoEnumParam(fe)
+		} else if paramType, ok := crossFieldTags[tag]; ok {
+			param = formatCrossParam(param, paramType, loc, func(name string) string { return name })
+		}
+		t, _ := ut.T(tag, fe.Field(), param)
+		return t
+	})
+}
+
+// 按规则参数格式拼接,字段名通过fieldName转换
+func formatCrossParam(param string, paramType int, loc *NValiderLocale, fieldName func(string) string) string {
+	items := strings.Fields(param)
+	if paramType == crossParamPairs {
+		conds := make([]string, 0, len(items)/2)
+		for i := 0; i+1 < len(items); i += 2 {
+			conds = append(conds, fieldName(items[i])+loc.PairSep+items[i+1])
+		}
+		return strings.Join(conds, loc.CondSep)
+	}
+	for i := range items {
+		items[i] = fieldName(items[i])
+	}
+	return strings.Join(items, loc.ListSep)
+}
+
+// 翻译跨字段规则,从校验对象的类型中找到参数引用的字段
+func (nvld *NValider) transCrossField(loc *NValiderLocale, rootType reflect.Type, fe validator.FieldError, field string) (string, bool) {
+	paramType, ok := crossFieldTags[fe.Tag()]
+	if !ok {
+		return "", false
+	}
+	parent := nvld.parentType(rootType, fe)
+	if parent == nil {
+		return "", false
+	}
+	param := formatCrossParam(fe.Param(), paramType, loc, func(name string) string {
+		fieldType := parent
+		names := strings.Split(name, ".")
+		for _, v := range names[:len(names)-1] {
+			if fieldType = structFieldType(fieldType, v); fieldType == nil {
+				return name
+			}
+		}
+		if fieldType = derefType(fieldType); fieldType.Kind() != reflect.Struct {
+			return name
+		}
+		fld, ok := fieldType.FieldByName(names[len(names)-1])
+		if !ok {
+			return name
+		}
+		if loc.FieldWithDesc {
+			return nvld.fieldName(fld)
+		}
+		return nvld.plainFieldName(fld)
+	})
+	t, err := loc.Trans.T(fe.Tag(), field, param)
+	return t, err == nil
+}
+
+// 出错字段所在结构体的类型,StructNamespace首段为类型名,末段为当前字段
+func (nvld *NValider) parentType(rootType reflect.Type, fe validator.FieldError) reflect.Type {
+	if rootType == nil {
+		return nil
+	}
+	segs := strings.Split(fe.StructNamespace(), ".")
+	parent := derefType(rootType)
+	for _, seg := range segs[1 : len(segs)-1] {
+		if parent = structFieldType(parent, seg); parent == nil {
+			return nil
+		}
+	}
+	if parent.Kind() != reflect.Struct {
+		return nil
+	}
+	return parent
+}
+
+// 结构体中字段的类型,seg可带[下标]
+func structFieldType(t reflect.Type, seg string) reflect.Type {
+	name, index, _ := strings.Cut(seg, "[")
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	fld, ok := t.FieldByName(name)
+	if !ok {
+		return nil
+	}
+	t = derefType(fld.Type)
+	for ; index != ""; _, index, _ = strings.Cut(index, "[") {
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			t = derefType(t.Elem())
+		default:
+			return nil
+		}
+	}
+	return t
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -19,6 +19,11 @@ import (
 type NValider struct {
 	Validate *validator.Validate
 	ZhTrans  ut.Translator
+	// 未指定或未匹配到语言时使用,默认zh
+	DefaultLocale string
+	locales       map[string]*NValiderLocale
+	tagJsonName   string
+	tagZhdescName string
 }
 
 func NewNValider(tagJsonName, tagZhdescName string) *NValider {
@@ -30,31 +35,43 @@ func NewNValider(tagJsonName, tagZhdescName string) *NValider {
 		zhTrans, _ := ut.New(localZh, localZh).GetTranslator("zh")
 		// 注册中文翻译
 		zh_translations.RegisterDefaultTranslations(validate, zhTrans)
+		nvld := &NValider{Validate: validate, ZhTrans: zhTrans, DefaultLocale: "zh", locales: map[string]*NValiderLocale{}, tagJsonName: tagJsonName, tagZhdescName: tagZhdescName}
 		// 注册自定义字段名映射_通过tag[zhdesc]将中文字段标准出来
-		validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
-			tagJson := fld.Tag.Get(tagJsonName)
-			tagZhdesc := fld.Tag.Get(tagZhdescName)
-			if tagJson != "" && tagZhdesc != "" {
-				return fmt.Sprintf("%s[%s]", tagZhdesc, tagJson)
-			} else if tagJson != "" {
-				return tagJson
-			} else if tagZhdesc != "" {
-				return tagZhdesc
-			}
-			return fld.Name
-		})
+		validate.RegisterTagNameFunc(nvld.fieldName)
 		// 注册自定义类型
 		registerNullFunc(validate)
+		registerReqVoFunc(validate)
 
 		// 覆盖所有数值规则的翻译逻辑：移除千位分隔符
 		registerCustomFormat(validate, zhTrans)
 
-		return &NValider{Validate: validate, ZhTrans: zhTrans}
+		// 身份证号、手机号等内置规则
+		registerNRules(validate)
+		// 内置规则及跨字段规则的中英文翻译
+		nvld.AddLocale("zh", &NValiderLocale{Trans: zhTrans, FieldWithDesc: true, PairSep: "为", CondSep: "且", ListSep: "、"}, nZhRuleTrans)
+		registerEnLocale(nvld)
+
+		return nvld
 	} else {
 		panic(nerror.NewRunTimeError("检查binding.Validator.Engine()是否是*validator.Validate"))
 	}
 
 }
+
+// 字段显示名 zhdesc[json]
+func (nvld *NValider) fieldName(fld reflect.StructField) string {
+	tagJson, _, _ := strings.Cut(fld.Tag.Get(nvld.tagJsonName), ",")
+	tagZhdesc := fld.Tag.Get(nvld.tagZhdescName)
+	if tagJson != "" && tagZhdesc != "" {
+		return fmt.Sprintf("%s[%s]", tagZhdesc, tagJson)
+	} else if tagJson != "" {
+		return tagJson
+	} else if tagZhdesc != "" {
+		return tagZhdesc
+	}
+	return fld.Name
+}
+
 func registerCustomFormat(validate *validator.Validate, trans ut.Translator) {
 	// 覆盖所有数值规则的翻译逻辑
 	numberTagMap := map[string]string{
@@ -99,15 +116,12 @@ func registerNullFunc(validate *validator.Validate) {
 }
 
 func (nvld *NValider) TransErr2ZhErr(err error) error {
-	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		validErr := &NiexqValidErr{}
-		// 遍历每个错误并翻译
-		for _, e := range validationErrors {
-			validErr.ErrDescList = append(validErr.ErrDescList, e.Translate(nvld.ZhTrans)+";")
-		}
-		return validErr
-	}
-	return err
+	return nvld.TransErr("zh", nil, err)
+}
+
+// 按DefaultLocale翻译obj的校验错误,跨字段规则中引用的字段也按当前语言显示
+func (nvld *NValider) TransStructErr(obj any, err error) error {
+	return nvld.TransErr(nvld.DefaultLocale, obj, err)
 }
 
 func (nvld *NValider) ValidStrct(obj any) error {
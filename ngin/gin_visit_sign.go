@@ -0,0 +1,135 @@
+package ngin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ndnen"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/nyaml"
+	"github.com/tjfoc/gmsm/sm2"
+)
+
+const (
+	VisitSignTypeHmacSm3 = "hmac-sm3"
+	VisitSignTypeSm2     = "sm2"
+)
+
+// 记录已使用的Onece-Str,RedisService 与 MemCacheService 均已实现
+type NVisitSignNonceCache interface {
+	// 仅在key不存在时写入成功
+	PutNxExStr(key string, val string, sencond int) error
+}
+
+type visitSrcKey struct {
+	signType  string
+	hmacKey   string
+	sm2PubKey *sm2.PublicKey
+}
+
+// 待签名的字符串,各部分以\n连接
+// 请求方法\n路径(含Query)\nBody的SM3小写Hex\nClient-Time\nOnece-Str
+func VisitSignSrcStr(method, requestUri string, body []byte, clientTime, onceStr string) string {
+	bodyHash := hex.EncodeToString(ndnen.Sm3hash(body))
+	return strings.Join([]string{strings.ToUpper(method), requestUri, bodyHash, clientTime, onceStr}, "\n")
+}
+
+// 验证请求签名
+// Client-Time 为毫秒或秒级时间戳,与服务器时间误差超过TimeWindow时拒绝
+// Onece-Str 在2倍TimeWindow内只能使用一次
+// Visit-Sign 按Visit-Src配置的方式签名: hmac-sm3为小写Hex,sm2为Base64的DER签名
+// 验证失败返回RespCode_Sign_Err
+func VisitSignHandlerFunc(conf *nyaml.YamlConfVisitSign, nonceCache NVisitSignNonceCache) gin.HandlerFunc {
+	slog.Debug("Add Middleware VisitSignHandlerFunc")
+	srcKeys := map[string]*visitSrcKey{}
+	for _, v := range conf.VisitSrcKeys {
+		key := &visitSrcKey{signType: strings.ToLower(v.SignType), hmacKey: v.HmacKey}
+		switch key.signType {
+		case VisitSignTypeHmacSm3:
+			if v.HmacKey == "" {
+				panic(nerror.NewRunTimeErrorFmt("Visit-Src[%s]未配置hmacKey", v.VisitSrc))
+			}
+		case VisitSignTypeSm2:
+			pubKey, err := ndnen.Sm2LoadPubKeyFromHex(v.Sm2HexPubKey)
+			if err != nil {
+				panic(nerror.NewRunTimeErrorWithError(fmt.Sprintf("Visit-Src[%s]的sm2HexPubKey错误", v.VisitSrc), err))
+			}
+			key.sm2PubKey = pubKey
+		default:
+			panic(nerror.NewRunTimeErrorFmt("Visit-Src[%s]的签名方式[%s]不支持", v.VisitSrc, v.SignType))
+		}
+		srcKeys[v.VisitSrc] = key
+	}
+	timeWindow := time.Duration(max(conf.TimeWindow, 1)) * time.Second
+
+	signFail := func(ctx *gin.Context, msg string) {
+		slog.Warn(fmt.Sprintf("%s\t%s\t签名验证失败:%s", ctx.Request.RequestURI, ctx.ClientIP(), msg))
+		ctx.AbortWithStatusJSON(http.StatusOK, NewNoBaseResp(RespCode_Sign_Err, msg))
+	}
+
+	return func(ctx *gin.Context) {
+		header := ctx.Request.Header
+		clientTime := header.Get("Client-Time")
+		onceStr := header.Get("Onece-Str")
+		visitSrc := header.Get("Visit-Src")
+		visitSign := header.Get("Visit-Sign")
+		if clientTime == "" || onceStr == "" || visitSrc == "" || visitSign == "" {
+			signFail(ctx, "缺少签名参数")
+			return
+		}
+		srcKey, ok := srcKeys[visitSrc]
+		if !ok {
+			signFail(ctx, fmt.Sprintf("未知的请求源[%s]", visitSrc))
+			return
+		}
+		reqTime, err := parseClientTime(clientTime)
+		if err != nil {
+			signFail(ctx, err.Error())
+			return
+		}
+		if diff := time.Since(reqTime); diff > timeWindow || diff < -timeWindow {
+			signFail(ctx, "请求时间已过期")
+			return
+		}
+		body := readAndResetBody(ctx)
+		if ctx.IsAborted() {
+			return
+		}
+		srcStr := VisitSignSrcStr(ctx.Request.Method, ctx.Request.RequestURI, body, clientTime, onceStr)
+		var verified bool
+		if srcKey.signType == VisitSignTypeSm2 {
+			verified = ndnen.Sm2VerifyByPubKey(srcKey.sm2PubKey, srcStr, visitSign)
+		} else {
+			verified = ndnen.HmacSm3VerifyHex(srcKey.hmacKey, srcStr, visitSign)
+		}
+		if !verified {
+			signFail(ctx, "签名错误")
+			return
+		}
+		// 签名通过后再记录Onece-Str,避免伪造的请求占用
+		nonceKey := fmt.Sprintf("%s%s:%s", conf.NoncePrefix, visitSrc, onceStr)
+		if err := nonceCache.PutNxExStr(nonceKey, clientTime, int(2*timeWindow/time.Second)); err != nil {
+			signFail(ctx, "重复的请求")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// 毫秒(13位)或秒(10位)级时间戳
+func parseClientTime(clientTime string) (time.Time, error) {
+	ts, err := strconv.ParseInt(clientTime, 10, 64)
+	if err != nil {
+		return time.Time{}, nerror.NewRunTimeErrorFmt("Client-Time[%s]不是时间戳", clientTime)
+	}
+	if len(clientTime) > 10 {
+		return time.UnixMilli(ts), nil
+	}
+	return time.Unix(ts, 0), nil
+}
@@ -0,0 +1,299 @@
+package ngin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/njson"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+// WebSocket连接管理,按分组广播
+type NWsHub struct {
+	// 每个连接的发送队列长度,队列满时关闭该连接
+	SendQueueSize int
+	// 发送ping的间隔
+	PingInterval time.Duration
+	// 超过该时间未收到任何消息(含pong)时关闭连接,需大于PingInterval
+	PongTimeout time.Duration
+	// 单次写出的超时时间
+	WriteTimeout time.Duration
+	// 接收消息的最大字节数
+	MaxMessageSize int64
+	// 收到客户端消息,执行时已设置连接的跟踪ID
+	OnMessage func(conn *NWsConn, msgType int, data []byte)
+	// 连接关闭
+	OnClose func(conn *NWsConn)
+
+	upgrader websocket.Upgrader
+	mu       sync.RWMutex
+	conns    map[*NWsConn]struct{}
+	groups   map[string]map[*NWsConn]struct{}
+}
+
+// WebSocket连接
+type NWsConn struct {
+	Id string
+	// 登录用户,在AuthHandlerFunc之后升级时有值
+	Principal *NGinPrincipal
+	// 升级请求的跟踪ID
+	TraceId string
+
+	hub        *NWsHub
+	ws         *websocket.Conn
+	send       chan wsMessage
+	done       chan struct{}
+	closeOnce  sync.Once
+	w3cTraceId string
+}
+
+type wsMessage struct {
+	msgType int
+	data    []byte
+}
+
+// 创建连接管理
+// checkOrigin 校验升级请求的Origin,为nil时只允许同源
+func NewNWsHub(checkOrigin func(r *http.Request) bool) *NWsHub {
+	return &NWsHub{
+		SendQueueSize:  64,
+		PingInterval:   30 * time.Second,
+		PongTimeout:    60 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		MaxMessageSize: 64 << 10,
+		upgrader:       websocket.Upgrader{CheckOrigin: checkOrigin},
+		conns:          map[*NWsConn]struct{}{},
+		groups:         map[string]map[*NWsConn]struct{}{},
+	}
+}
+
+// 升级为WebSocket的处理函数,处理函数阻塞到连接关闭
+// onConnect 升级成功后执行,可加入分组,返回错误时关闭连接
+func (hub *NWsHub) HandlerFunc(onConnect func(conn *NWsConn) error) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ws, err := hub.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			// Upgrade已写入错误响应
+			slog.Debug(fmt.Sprintf("WebSocket升级失败:%v", err))
+			ctx.Abort()
+			return
+		}
+		conn := &NWsConn{
+			Id:         ntools.UUIDStr(false),
+			TraceId:    ntools.SlogGetTraceId(),
+			w3cTraceId: ntools.SlogGetW3cTraceId(),
+			hub:        hub,
+			ws:         ws,
+			send:       make(chan wsMessage, hub.SendQueueSize),
+			done:       make(chan struct{}),
+		}
+		conn.Principal, _ = GetPrincipal(ctx)
+		hub.mu.Lock()
+		hub.conns[conn] = struct{}{}
+		hub.mu.Unlock()
+		if onConnect != nil {
+			if err := onConnect(conn); err != nil {
+				slog.Debug(fmt.Sprintf("WebSocket连接被拒绝:%v", err))
+				conn.Close()
+				return
+			}
+		}
+		go conn.writePump()
+		conn.readPump()
+	}
+}
+
+func (conn *NWsConn) readPump() {
+	defer conn.Close()
+	hub := conn.hub
+	conn.ws.SetReadLimit(hub.MaxMessageSize)
+	conn.ws.SetReadDeadline(time.Now().Add(hub.PongTimeout))
+	conn.ws.SetPongHandler(func(string) error {
+		return conn.ws.SetReadDeadline(time.Now().Add(hub.PongTimeout))
+	})
+	for {
+		msgType, data, err := conn.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Debug(fmt.Sprintf("WebSocket[%s]读取失败:%v", conn.Id, err))
+			}
+			return
+		}
+		conn.ws.SetReadDeadline(time.Now().Add(hub.PongTimeout))
+		if hub.OnMessage != nil {
+			ntools.SlogSetTraceContext(conn.TraceId, conn.w3cTraceId)
+			hub.OnMessage(conn, msgType, data)
+		}
+	}
+}
+
+func (conn *NWsConn) writePump() {
+	ticker := time.NewTicker(conn.hub.PingInterval)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+	for {
+		select {
+		case <-conn.done:
+			return
+		case msg := <-conn.send:
+			conn.ws.SetWriteDeadline(time.Now().Add(conn.hub.WriteTimeout))
+			if err := conn.ws.WriteMessage(msg.msgType, msg.data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(conn.hub.WriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// 发送文本消息,放入发送队列后立即返回,队列满时关闭连接
+func (conn *NWsConn) Send(data []byte) error {
+	return conn.enqueue(wsMessage{msgType: websocket.TextMessage, data: data})
+}
+
+// 发送JSON
+func (conn *NWsConn) SendJson(obj any) error {
+	data, err := njson.Obj2JsonBytes(obj)
+	if err != nil {
+		return err
+	}
+	return conn.Send(data)
+}
+
+func (conn *NWsConn) enqueue(msg wsMessage) error {
+	select {
+	case <-conn.done:
+		return nerror.NewRunTimeError("WebSocket连接已关闭")
+	default:
+	}
+	select {
+	case conn.send <- msg:
+		return nil
+	default:
+		slog.Warn(fmt.Sprintf("WebSocket[%s]发送队列已满,关闭连接", conn.Id))
+		conn.Close()
+		return nerror.NewRunTimeError("WebSocket发送队列已满")
+	}
+}
+
+// 加入分组
+func (conn *NWsConn) Join(groups ...string) {
+	hub := conn.hub
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if _, ok := hub.conns[conn]; !ok {
+		return
+	}
+	for _, group := range groups {
+		members, ok := hub.groups[group]
+		if !ok {
+			members = map[*NWsConn]struct{}{}
+			hub.groups[group] = members
+		}
+		members[conn] = struct{}{}
+	}
+}
+
+// 退出分组
+func (conn *NWsConn) Leave(groups ...string) {
+	hub := conn.hub
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, group := range groups {
+		hub.leave(conn, group)
+	}
+}
+
+// 调用时需持有hub.mu
+func (hub *NWsHub) leave(conn *NWsConn, group string) {
+	if members, ok := hub.groups[group]; ok {
+		delete(members, conn)
+		if len(members) == 0 {
+			delete(hub.groups, group)
+		}
+	}
+}
+
+// 关闭连接并退出全部分组
+func (conn *NWsConn) Close() {
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+		hub := conn.hub
+		hub.mu.Lock()
+		delete(hub.conns, conn)
+		for group := range hub.groups {
+			hub.leave(conn, group)
+		}
+		hub.mu.Unlock()
+		conn.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		conn.ws.Close()
+		if hub.OnClose != nil {
+			hub.OnClose(conn)
+		}
+	})
+}
+
+// 向分组广播,group为空时向全部连接广播,返回放入发送队列的连接数
+func (hub *NWsHub) Broadcast(group string, data []byte) int {
+	hub.mu.RLock()
+	members := hub.conns
+	if group != "" {
+		members = hub.groups[group]
+	}
+	targets := make([]*NWsConn, 0, len(members))
+	for conn := range members {
+		targets = append(targets, conn)
+	}
+	hub.mu.RUnlock()
+	count := 0
+	for _, conn := range targets {
+		if conn.Send(data) == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// 向分组广播JSON
+func (hub *NWsHub) BroadcastJson(group string, obj any) (int, error) {
+	data, err := njson.Obj2JsonBytes(obj)
+	if err != nil {
+		return 0, err
+	}
+	return hub.Broadcast(group, data), nil
+}
+
+// 连接数,group为空时为全部连接数
+func (hub *NWsHub) Count(group string) int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if group == "" {
+		return len(hub.conns)
+	}
+	return len(hub.groups[group])
+}
+
+// 关闭全部连接,可通过nGin.OnShutdown注册
+func (hub *NWsHub) Shutdown(ctx context.Context) error {
+	hub.mu.RLock()
+	targets := make([]*NWsConn, 0, len(hub.conns))
+	for conn := range hub.conns {
+		targets = append(targets, conn)
+	}
+	hub.mu.RUnlock()
+	for _, conn := range targets {
+		conn.Close()
+	}
+	return nil
+}
@@ -37,13 +37,20 @@ func NewNMqConsumer(nameSvrAddr, topic, groupName string, broadCastingMode bool)
 }
 
 // 订阅消息,tag可以为空
+// onMsg执行前按消息属性还原跟踪ID,消息未携带时使用MQ_+MsgId
 func (mq *NMqConsumer) Subscribe(tag string, onMsg func(ctx context.Context, msg *primitive.MessageExt) (consumer.ConsumeResult, error)) error {
 	msgSelector := consumer.MessageSelector{}
 	if tag != "" {
 		msgSelector = consumer.MessageSelector{Type: consumer.TAG, Expression: tag}
 	}
 	err := mq.Consumer.Subscribe(mq.Topic, msgSelector, func(ctx context.Context, imsgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
-		return onMsg(ctx, imsgs[0])
+		msg := imsgs[0]
+		traceId, w3cTraceId := ntools.TraceFromCarrier(msg.GetProperty(NMqPropTraceId), msg.GetProperty(NMqPropTraceParent))
+		if traceId == "" {
+			traceId = "MQ_" + msg.MsgId
+		}
+		ntools.SlogSetTraceContext(traceId, w3cTraceId)
+		return onMsg(ctx, msg)
 	})
 	if nil != err {
 		return err
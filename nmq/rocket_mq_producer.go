@@ -3,12 +3,14 @@ package nmq
 import (
 	"context"
 	"log/slog"
+	"sync/atomic"
 
 	"github.com/apache/rocketmq-client-go/v2"
 	"github.com/apache/rocketmq-client-go/v2/primitive"
 	"github.com/apache/rocketmq-client-go/v2/producer"
 	"github.com/apache/rocketmq-client-go/v2/rlog"
 	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/ntools"
 )
 
 type NMqProduer struct {
@@ -16,8 +18,16 @@ type NMqProduer struct {
 	Topic     string
 	Producer  rocketmq.Producer
 	Consumer  rocketmq.PushConsumer
+	// NewNMqProduer启动成功后为true,Shutdown后为false
+	running atomic.Bool
 }
 
+// 消息属性中的跟踪ID与traceparent
+const (
+	NMqPropTraceId     = "traceId"
+	NMqPropTraceParent = "traceparent"
+)
+
 type NMqProperty struct {
 	Key string
 	Val string
@@ -37,10 +47,19 @@ func NewNMqProduer(nameSvrAddr, topic, groupName string) (*NMqProduer, error) {
 		return nil, nerror.NewRunTimeErrorWithError("生产者启动失败", err)
 	}
 	slog.Debug("生产者已启动")
-	return &NMqProduer{GroupName: groupName, Topic: topic, Producer: myProducer}, nil
+	mq := &NMqProduer{GroupName: groupName, Topic: topic, Producer: myProducer}
+	mq.running.Store(true)
+	return mq, nil
+}
+
+// 关闭生产者,需通过该方法关闭Ready才能感知
+func (mq *NMqProduer) Shutdown() error {
+	mq.running.Store(false)
+	return mq.Producer.Shutdown()
 }
 
 // 发送顺序消息，tag可以为空
+// 当前协程的跟踪ID与traceparent写入消息属性,prp中已指定的不覆盖
 func (mq *NMqProduer) SendOrderMsg(msgContent, shardingKey, tag string, prp ...NMqProperty) (msgId string, err error) {
 	msg := &primitive.Message{
 		Topic: mq.Topic,
@@ -48,6 +67,10 @@ func (mq *NMqProduer) SendOrderMsg(msgContent, shardingKey, tag string, prp ...N
 	}
 	msg.WithShardingKey(shardingKey)
 	msg.WithTag(tag)
+	if traceId := ntools.SlogGetTraceId(); traceId != "" {
+		msg.WithProperty(NMqPropTraceId, traceId)
+		msg.WithProperty(NMqPropTraceParent, ntools.TraceParentStr())
+	}
 	if len(prp) > 0 {
 		for _, v := range prp {
 			msg.WithProperty(v.Key, v.Val)
@@ -59,3 +82,12 @@ func (mq *NMqProduer) SendOrderMsg(msgContent, shardingKey, tag string, prp ...N
 	}
 	return res.MsgID, nil
 }
+
+// 生产者是否在运行,可用于就绪检查
+// 只检查NewNMqProduer启动及Shutdown记录的状态,不检查Broker是否可达
+func (mq *NMqProduer) Ready() error {
+	if !mq.running.Load() {
+		return nerror.NewRunTimeError("生产者未启动或已关闭")
+	}
+	return nil
+}
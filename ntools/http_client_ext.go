@@ -7,6 +7,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/niexqc/nlibs/njson"
@@ -43,7 +44,11 @@ func (e *httpClientExt) GetText(url string, timeOut time.Duration) (string, erro
 func (e *httpClientExt) Get(url string, timeOut time.Duration) ([]byte, error) {
 
 	client := &http.Client{Timeout: timeOut, Transport: &http.Transport{DisableKeepAlives: true, Proxy: localProxy}}
-	resp, err := client.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doWithTrace(client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +81,7 @@ func (e *httpClientExt) Post(url string, data interface{}, contentType string, t
 	client := &http.Client{Timeout: timeOut, Transport: &http.Transport{DisableKeepAlives: true, Proxy: localProxy}}
 	jsonStr, _ := njson.ObjToJSONBytesByGoJson(data)
 
-	resp, err := client.Post(url, contentType, bytes.NewBuffer(*jsonStr))
+	resp, err := postWithTrace(client, url, contentType, bytes.NewBuffer(*jsonStr))
 	if err != nil {
 		return "", err
 	}
@@ -95,7 +100,7 @@ func (e *httpClientExt) Post(url string, data interface{}, contentType string, t
 func (e *httpClientExt) PostForm(reqUrl string, data url.Values, timeOut time.Duration) (string, error) {
 	// 超时时间：5秒
 	client := &http.Client{Timeout: timeOut, Transport: &http.Transport{DisableKeepAlives: true, Proxy: localProxy}}
-	resp, err := client.PostForm(reqUrl, data)
+	resp, err := postWithTrace(client, reqUrl, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", err
 	}
@@ -123,7 +128,7 @@ func (e *httpClientExt) PostFile(reqUrl string, fileBytes *[]byte, fileNameParam
 	contentType := bodyWriter.FormDataContentType()
 	bodyWriter.Close()
 
-	resp, err := client.Post(reqUrl, contentType, bodyBuffer)
+	resp, err := postWithTrace(client, reqUrl, contentType, bodyBuffer)
 	if err != nil {
 		return "", err
 	}
@@ -134,3 +139,18 @@ func (e *httpClientExt) PostFile(reqUrl string, fileBytes *[]byte, fileNameParam
 	result, _ := io.ReadAll(resp.Body)
 	return string(result), nil
 }
+
+// 携带当前协程的跟踪信息发送请求
+func doWithTrace(client *http.Client, req *http.Request) (*http.Response, error) {
+	TraceInjectHeader(req.Header)
+	return client.Do(req)
+}
+
+func postWithTrace(client *http.Client, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return doWithTrace(client, req)
+}
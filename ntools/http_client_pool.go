@@ -33,8 +33,12 @@ func NewNHttpClientPool(maxConcurrent, maxIdleConns int, httpTimeout, idleConnTi
 	return o
 }
 
+// 携带调用方协程的跟踪信息,callback中的日志使用同一个跟踪ID
 func (hcp *NHttpClientPool) RunRequst(request *http.Request, callback func(resp *http.Response, err error)) {
+	TraceInjectHeader(request.Header)
+	traceId, w3cTraceId := SlogGetTraceId(), traceParentLocal.Get()
 	hcp.AntsPool.Submit(func() {
+		SlogSetTraceContext(traceId, w3cTraceId)
 		httpClient := hcp.HttpClientPool.Get().(*http.Client)
 		defer hcp.HttpClientPool.Put(httpClient)
 
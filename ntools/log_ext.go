@@ -28,8 +28,9 @@ type NwLogHandler struct {
 	fileWriter io.Writer
 }
 
+// 设置当前协程的跟踪ID,W3C trace-id在需要时随机生成
 func SlogSetTraceId(traceId string) {
-	threadLocal.Set(traceId)
+	SlogSetTraceContext(traceId, "")
 }
 
 func SlogGetTraceId() string {
@@ -119,7 +120,7 @@ func (h *NwLogHandler) Handle(ctx context.Context, r slog.Record) (err error) {
 	sb.WriteString(r.Message + " ")
 
 	r.Attrs(func(a slog.Attr) bool {
-		sb.WriteString(a.String())
+		sb.WriteString(a.String() + " ")
 		return true
 	})
 
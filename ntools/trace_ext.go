@@ -0,0 +1,133 @@
+package ntools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/timandy/routine"
+)
+
+const (
+	// 跟踪ID的请求头/响应头
+	TraceIdHeader = "X-Trace-Id"
+	// W3C Trace Context的请求头/响应头
+	TraceParentHeader = "traceparent"
+)
+
+// 当前协程的W3C trace-id(32位hex)
+var traceParentLocal = routine.NewInheritableThreadLocal[string]()
+
+// 外部传入的跟踪ID只允许字母、数字与 -_.: ,避免日志注入
+var traceIdReg = regexp.MustCompile(`^[0-9A-Za-z\-_.:]{1,64}$`)
+
+// W3C traceparent: version-traceId-parentId-flags
+type TraceParent struct {
+	Version  string
+	TraceId  string
+	ParentId string
+	Flags    string
+}
+
+func (tp *TraceParent) String() string {
+	return fmt.Sprintf("%s-%s-%s-%s", tp.Version, tp.TraceId, tp.ParentId, tp.Flags)
+}
+
+// 解析traceparent,格式错误或trace-id/parent-id全为0时返回false
+func ParseTraceParent(val string) (*TraceParent, bool) {
+	parts := strings.Split(strings.TrimSpace(val), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return nil, false
+	}
+	for _, part := range parts[:4] {
+		if !isLowerHex(part) {
+			return nil, false
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return nil, false
+	}
+	return &TraceParent{Version: parts[0], TraceId: parts[1], ParentId: parts[2], Flags: parts[3]}, true
+}
+
+func isLowerHex(val string) bool {
+	for _, c := range val {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// 随机的W3C trace-id
+func NewW3cTraceId() string {
+	return randomHex(16)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// 设置当前协程的跟踪ID与W3C trace-id,w3cTraceId为空时在需要时随机生成
+func SlogSetTraceContext(traceId, w3cTraceId string) {
+	threadLocal.Set(traceId)
+	traceParentLocal.Set(w3cTraceId)
+}
+
+// 当前协程的W3C trace-id,已设置跟踪ID但未设置时随机生成
+func SlogGetW3cTraceId() string {
+	w3cTraceId := traceParentLocal.Get()
+	if w3cTraceId == "" && SlogGetTraceId() != "" {
+		w3cTraceId = NewW3cTraceId()
+		traceParentLocal.Set(w3cTraceId)
+	}
+	return w3cTraceId
+}
+
+// 当前协程的traceparent,每次调用生成新的parent-id,未设置跟踪ID时返回空
+func TraceParentStr() string {
+	w3cTraceId := SlogGetW3cTraceId()
+	if w3cTraceId == "" {
+		return ""
+	}
+	return (&TraceParent{Version: "00", TraceId: w3cTraceId, ParentId: randomHex(8), Flags: "01"}).String()
+}
+
+// 从传入的X-Trace-Id与traceparent还原跟踪ID与W3C trace-id
+// X-Trace-Id不合法时丢弃,为空时使用traceparent中的trace-id
+func TraceFromCarrier(traceId, traceParent string) (string, string) {
+	traceId = strings.TrimSpace(traceId)
+	if !traceIdReg.MatchString(traceId) {
+		traceId = ""
+	}
+	w3cTraceId := ""
+	if tp, ok := ParseTraceParent(traceParent); ok {
+		w3cTraceId = tp.TraceId
+	}
+	if traceId == "" {
+		traceId = w3cTraceId
+	}
+	return traceId, w3cTraceId
+}
+
+// 当前协程的跟踪信息写入请求头,已存在的不覆盖
+func TraceInjectHeader(header http.Header) {
+	traceId := SlogGetTraceId()
+	if traceId == "" {
+		return
+	}
+	if header.Get(TraceIdHeader) == "" {
+		header.Set(TraceIdHeader, traceId)
+	}
+	if header.Get(TraceParentHeader) == "" {
+		header.Set(TraceParentHeader, TraceParentStr())
+	}
+}
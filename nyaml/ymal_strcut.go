@@ -18,6 +18,12 @@ type YamlConfRedis struct {
 	MaxIdle        int    `yaml:"maxIdle" hc:"MaxIdle"`
 	MaxActive      int    `yaml:"maxActive" hc:"MaxActive"`
 	IdleTimeout    int    `yaml:"idleTimeout" hc:"IdleTimeout-秒"`
+
+	RedisMode      string   `yaml:"redisMode" hc:"部署模式: standalone|sentinel|cluster,默认standalone"`
+	SentinelAddrs  []string `yaml:"sentinelAddrs" hc:"sentinel模式下Sentinel的地址列表 host:port"`
+	SentinelMaster string   `yaml:"sentinelMaster" hc:"sentinel模式下监控的主节点名称"`
+	SentinelPwd    string   `yaml:"sentinelPwd" hc:"sentinel模式下Sentinel的密码,没有则为空"`
+	ClusterAddrs   []string `yaml:"clusterAddrs" hc:"cluster模式下种子节点的地址列表 host:port"`
 }
 
 type YamlConfSqlPrint struct {
@@ -59,6 +65,67 @@ type YamlConfEndnKey struct {
 	Sm2HexPriKey string `yaml:"sm2HexPriKey" hc:"服务端SM2私钥"`
 }
 
+type YamlConfVisitSign struct {
+	TimeWindow   int                   `yaml:"timeWindow" hc:"Client-Time与服务器时间允许的误差-秒"`
+	NoncePrefix  string                `yaml:"noncePrefix" hc:"缓存Onece-Str防重放的Key前缀"`
+	VisitSrcKeys []YamlConfVisitSrcKey `yaml:"visitSrcKeys" hc:"各请求源(Visit-Src)的签名密钥"`
+}
+
+type YamlConfVisitSrcKey struct {
+	VisitSrc     string `yaml:"visitSrc" hc:"请求发起的源,对应Header中的Visit-Src"`
+	SignType     string `yaml:"signType" hc:"签名方式: hmac-sm3|sm2"`
+	HmacKey      string `yaml:"hmacKey" hc:"hmac-sm3的密钥"`
+	Sm2HexPubKey string `yaml:"sm2HexPubKey" hc:"sm2验签的公钥Hex"`
+}
+
+type YamlConfCors struct {
+	AllowOrigins     []string `yaml:"allowOrigins" hc:"允许的来源,*为全部,支持通配子域名 https://*.example.com"`
+	AllowMethods     []string `yaml:"allowMethods" hc:"允许的方法,为空时GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS"`
+	AllowHeaders     []string `yaml:"allowHeaders" hc:"允许的请求头,为空时允许预检请求中的全部请求头"`
+	ExposeHeaders    []string `yaml:"exposeHeaders" hc:"允许前端读取的响应头"`
+	AllowCredentials bool     `yaml:"allowCredentials" hc:"是否允许携带Cookie等凭证,allowOrigins为*时不能开启"`
+	MaxAge           int      `yaml:"maxAge" hc:"预检请求的缓存时间-秒"`
+}
+
+type YamlConfSecurityHeaders struct {
+	HstsMaxAge            int    `yaml:"hstsMaxAge" hc:"Strict-Transport-Security的max-age-秒,0为不设置"`
+	HstsIncludeSubDomains bool   `yaml:"hstsIncludeSubDomains" hc:"HSTS是否包含子域名"`
+	HstsPreload           bool   `yaml:"hstsPreload" hc:"HSTS是否preload"`
+	FrameOptions          string `yaml:"frameOptions" hc:"X-Frame-Options: DENY|SAMEORIGIN,为空时DENY"`
+	ContentSecurityPolicy string `yaml:"contentSecurityPolicy" hc:"Content-Security-Policy,为空时不设置"`
+	ReferrerPolicy        string `yaml:"referrerPolicy" hc:"Referrer-Policy,为空时strict-origin-when-cross-origin"`
+}
+
+type YamlConfIpAcl struct {
+	AllowCidrs     []string `yaml:"allowCidrs" hc:"允许的IP或CIDR,为空时允许未被禁止的全部IP"`
+	DenyCidrs      []string `yaml:"denyCidrs" hc:"禁止的IP或CIDR,优先于allowCidrs"`
+	TrustedProxies []string `yaml:"trustedProxies" hc:"可信代理的IP或CIDR,为空时使用连接的IP"`
+}
+
+type YamlConfAdmin struct {
+	AllowCidrs     []string `yaml:"allowCidrs" hc:"允许访问管理接口的IP或CIDR"`
+	TrustedProxies []string `yaml:"trustedProxies" hc:"可信代理的IP或CIDR,为空时使用连接的IP"`
+	Token          string   `yaml:"token" hc:"访问管理接口的Token,请求头Admin-Token或Authorization: Bearer,IP在allowCidrs中时不需要"`
+	ReadyTimeout   int      `yaml:"readyTimeout" hc:"就绪检查的超时时间-秒,为0时3秒"`
+}
+
+type YamlConfAccessLog struct {
+	ShowReqBody  bool     `yaml:"showReqBody" hc:"是否打印请求体(json/text/xml)"`
+	ShowRespBody bool     `yaml:"showRespBody" hc:"是否打印响应体(json/text/xml)"`
+	MaxBodySize  int      `yaml:"maxBodySize" hc:"打印请求体/响应体的最大字节数,超过时截断,为0时2048"`
+	MaskKeys     []string `yaml:"maskKeys" hc:"按字段名脱敏(不区分大小写)[password,idCard,phone]"`
+	MaskPaths    []string `yaml:"maskPaths" hc:"按JSON路径脱敏,以.分隔,数组自动展开,*匹配任意字段[data.user.idCard]"`
+}
+
+type YamlConfUpload struct {
+	MaxSizeMb      int64    `yaml:"maxSizeMb" hc:"单个文件最大MB"`
+	AllowExts      []string `yaml:"allowExts" hc:"允许的扩展名[.jpg,.png],为空时不限制"`
+	AllowMimeTypes []string `yaml:"allowMimeTypes" hc:"允许的文件类型(按内容识别)[image/*,application/pdf],为空时不限制"`
+	LocalDir       string   `yaml:"localDir" hc:"保存到本地时的目录"`
+	UrlPrefix      string   `yaml:"urlPrefix" hc:"访问地址的前缀,为空时返回对象键"`
+	OssChunkSizeMb int64    `yaml:"ossChunkSizeMb" hc:"保存到OSS时超过该大小使用分片上传-MB"`
+}
+
 type YamlConfNAliOssConf struct {
 	InternalEndpoint       bool   `yaml:"internalEndpoint" hc:"程序是否运行在OSS所在地域内网"`
 	BucketName             string `yaml:"bucketName" hc:"Bucket名称"`
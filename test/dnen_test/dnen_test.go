@@ -2,6 +2,7 @@ package ndnen_test
 
 import (
 	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/niexqc/nlibs/ndnen"
@@ -190,3 +191,10 @@ func TestSm4GenHexIv(t *testing.T) {
 	ntools.TestEq(t, "TestSm4GenHexIv", 32, len(key))
 	ntools.TestEq(t, "TestSm4GenHexIv", 32, len(iv))
 }
+
+func TestHmacSm3(t *testing.T) {
+	sign := ndnen.HmacSm3Hex("key", "data")
+	ntools.TestEq(t, "测试 HmacSm3Hex,长度不匹配", 64, len(sign))
+	ntools.TestEq(t, "测试 HmacSm3VerifyHex,验签失败", true, ndnen.HmacSm3VerifyHex("key", "data", strings.ToUpper(sign)))
+	ntools.TestEq(t, "测试 HmacSm3VerifyHex,错误的密钥", false, ndnen.HmacSm3VerifyHex("key2", "data", sign))
+}
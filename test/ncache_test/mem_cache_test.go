@@ -1,9 +1,16 @@
 package ncache_test
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/niexqc/nlibs/ncache"
+	mencache "github.com/niexqc/nlibs/ncache/mem_cache"
+	rediscache "github.com/niexqc/nlibs/ncache/redis_cache"
 	"github.com/niexqc/nlibs/ntools"
 )
 
@@ -20,3 +27,99 @@ func TestMemPutExStr(t *testing.T) {
 	}
 	ntools.TestEq(t, "MemCacheService TestPutExStr 2秒后", "缓存不存在", err.Error())
 }
+
+func TestMemClearByKeyPrefix(t *testing.T) {
+	prefix := "TestMemClearByKeyPrefix"
+	for i := 1; i < 51; i++ {
+		memCacheService.PutExStr(fmt.Sprintf(prefix+"%d", i), "v", 500)
+	}
+	memCacheService.PutStr("TestMemClearByKeyPrefiy", "v")
+	clearNum, err := memCacheService.ClearByKeyPrefix(prefix)
+	ntools.TestErrPainic(t, "测试 TestMemClearByKeyPrefix", err)
+	ntools.TestEq(t, "测试 TestMemClearByKeyPrefix 删除数量", 50, clearNum)
+	ntools.TestEq(t, "测试 TestMemClearByKeyPrefix 其他前缀保留", true, memCacheService.ExistWithoutErr("TestMemClearByKeyPrefiy"))
+}
+
+func TestMemPutNxExStr(t *testing.T) {
+	err := memCacheService.PutNxExStr("TestMemPutNxExStr", "1", 1)
+	ntools.TestErrPainic(t, "测试 TestMemPutNxExStr", err)
+	err = memCacheService.PutNxExStr("TestMemPutNxExStr", "2", 1)
+	ntools.TestErrNotNil(t, "测试 TestMemPutNxExStr 已存在", err)
+}
+
+func TestMemLockRun(t *testing.T) {
+	//耗时操作设置为2秒,启动3个协程后等待1秒获取计数器结果只能为1，否则没有达到锁定的效果
+	var counter atomic.Int32
+	for i := range 3 {
+		go func(idx int) {
+			memCacheService.LockRun("memlock1", fmt.Sprintf("v%d", idx), 5, 6, 300, func() any {
+				counter.Add(1)
+				time.Sleep(2 * time.Second)
+				return nil
+			})
+		}(i)
+	}
+	time.Sleep(1 * time.Second)
+	ntools.TestEq(t, "TestMemLockRun 启动3个协程后等待1秒获取计数器结果只能为1", int32(1), counter.Load())
+}
+
+func TestMemProducerConsumer(t *testing.T) {
+	key := "TestMemProducerConsumer"
+	for i := 1; i < 4; i++ {
+		memCacheService.Producer(key, fmt.Sprintf("%d", i))
+	}
+	reciveChan := make(chan string, 1)
+	go memCacheService.Consumer(key, reciveChan)
+	recvs := []string{<-reciveChan, <-reciveChan, <-reciveChan}
+	ntools.TestEq(t, "TestMemProducerConsumer", "1,2,3", strings.Join(recvs, ","))
+}
+
+// 与RedisService的队列方法签名一致,可互相替换
+type memRedisQueue interface {
+	Producer(queueKey string, message string) error
+	Consumer(queueKey string, msgch chan string)
+}
+
+var _ memRedisQueue = (*mencache.MemCacheService)(nil)
+var _ memRedisQueue = (*rediscache.RedisService)(nil)
+
+func TestMemConsumerCtx(t *testing.T) {
+	key := "TestMemConsumerCtx"
+	for i := 1; i < 3; i++ {
+		memCacheService.Producer(key, fmt.Sprintf("%d", i))
+	}
+	reciveChan := make(chan string)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		memCacheService.ConsumerCtx(ctx, key, reciveChan)
+		close(stopped)
+	}()
+	ntools.TestEq(t, "TestMemConsumerCtx 读取", "1", <-reciveChan)
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("TestMemConsumerCtx ctx取消后未停止")
+	}
+	// 取消时未送出的消息放回队列
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	go memCacheService.ConsumerCtx(ctx2, key, reciveChan)
+	ntools.TestEq(t, "TestMemConsumerCtx 未送出的消息", "2", <-reciveChan)
+}
+
+func TestMemClearByKeyPrefixWithOnEvicted(t *testing.T) {
+	service := ncache.NewMemCacheService(time.Minute)
+	evicted := make(chan string, 1)
+	service.Cache.OnEvicted(func(key string, _ any) {
+		evicted <- key
+	})
+	service.PutStr("TestMemOnEvicted:1", "1")
+	service.PutStr("TestMemOnEvicted:2", "2")
+	service.ClearKey("TestMemOnEvicted:1")
+	ntools.TestEq(t, "TestMemClearByKeyPrefixWithOnEvicted 回调未被覆盖", "TestMemOnEvicted:1", <-evicted)
+	count, _ := service.ClearByKeyPrefix("TestMemOnEvicted:")
+	ntools.TestEq(t, "TestMemClearByKeyPrefixWithOnEvicted 清理数量", 1, count)
+}
@@ -1,8 +1,11 @@
 package ncache_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
@@ -130,3 +133,117 @@ func TestRedisProducerConsumer(t *testing.T) {
 	slog.Info("如果要接收所有的 序列测试方法会暂停")
 	// redisService.Consumer(key, reciveChan)
 }
+
+func TestRedisStreamQueue(t *testing.T) {
+	op1 := rediscache.RedisStreamQueueSetClaimIdle(200 * time.Millisecond)
+	op2 := rediscache.RedisStreamQueueSetBlockTime(200 * time.Millisecond)
+	op3 := rediscache.RedisStreamQueueSetMaxDelivery(2)
+	queue := redisService.NewStreamQueue("TestRedisStreamQueue", "g1", "c1", op1, op2, op3)
+	redisService.ClearKey("TestRedisStreamQueue")
+	redisService.ClearKey("TestRedisStreamQueue:dead")
+	err := queue.InitGroup()
+	ntools.TestErrPainic(t, "测试 TestRedisStreamQueue InitGroup", err)
+
+	queue.Produce("ok")
+	queue.Produce("fail")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	okCount := 0
+	queue.Consume(ctx, func(msg *rediscache.RedisStreamMsg) error {
+		if msg.Body == "fail" {
+			return errors.New("模拟处理失败")
+		}
+		okCount++
+		return nil
+	})
+	ntools.TestEq(t, "TestRedisStreamQueue 成功处理数量", 1, okCount)
+
+	pendingCount, err := queue.PendingCount()
+	ntools.TestErrPainic(t, "测试 TestRedisStreamQueue PendingCount", err)
+	ntools.TestEq(t, "TestRedisStreamQueue Pending数量", int64(0), pendingCount)
+
+	deads, err := queue.DeadLetters(10)
+	ntools.TestErrPainic(t, "测试 TestRedisStreamQueue DeadLetters", err)
+	ntools.TestEq(t, "TestRedisStreamQueue 死信数量", 1, len(deads))
+	ntools.TestEq(t, "TestRedisStreamQueue 死信内容", "fail", deads[0].Body)
+}
+
+func TestRedisDelayQueue(t *testing.T) {
+	op1 := rediscache.RedisDelayQueueSetPollInterval(100 * time.Millisecond)
+	queue := redisService.NewDelayQueue("TestRedisDelayQueue", op1)
+	queue.PushDelay("job1", "1", 500*time.Millisecond)
+	queue.PushDelay("job2", "2", time.Hour)
+	queue.PushDelay("job3", "3", 500*time.Millisecond)
+	ok, err := queue.Cancel("job3")
+	ntools.TestErrPainic(t, "测试 TestRedisDelayQueue Cancel", err)
+	ntools.TestEq(t, "TestRedisDelayQueue Cancel", true, ok)
+
+	jobs, _ := queue.PopDue()
+	ntools.TestEq(t, "TestRedisDelayQueue 未到期", 0, len(jobs))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	recvs := []string{}
+	queue.Consume(ctx, func(job *rediscache.RedisDelayJob) error {
+		recvs = append(recvs, job.Body)
+		return nil
+	})
+	ntools.TestEq(t, "TestRedisDelayQueue 到期任务", "1", strings.Join(recvs, ","))
+
+	size, _ := queue.Size()
+	ntools.TestEq(t, "TestRedisDelayQueue 剩余任务", int64(1), size)
+	queue.Cancel("job2")
+}
+
+func TestRedisDelayQueueAck(t *testing.T) {
+	queue := redisService.NewDelayQueue("TestRedisDelayQueueAck")
+	queue.PushDelay("job1", "1", 0)
+	jobs, err := queue.PopDue()
+	ntools.TestErrPainic(t, "测试 TestRedisDelayQueueAck PopDue", err)
+	ntools.TestEq(t, "TestRedisDelayQueueAck 取出", 1, len(jobs))
+
+	// 处理期间以相同ID重新写入,确认旧的投递不删除新任务
+	queue.PushDelay("job1", "2", time.Hour)
+	ok, err := queue.Ack(jobs[0])
+	ntools.TestErrPainic(t, "测试 TestRedisDelayQueueAck Ack", err)
+	ntools.TestEq(t, "TestRedisDelayQueueAck 不删除新任务", false, ok)
+	size, _ := queue.Size()
+	ntools.TestEq(t, "TestRedisDelayQueueAck 新任务保留", int64(1), size)
+	queue.Cancel("job1")
+}
+
+func TestRedisMutexReentrantAndWatchdog(t *testing.T) {
+	op1 := rediscache.RedisMutexSetReentrant(true)
+	op2 := rediscache.RedisMutexSetWatchdog(300 * time.Millisecond)
+	result, err := redisService.LockRun("TestRedisMutexReentrant", "owner1", 1, 3, 100, func() any {
+		//同一持有者可重入
+		inner, err := redisService.LockRun("TestRedisMutexReentrant", "owner1", 1, 0, 100, func() any {
+			//超过过期时间,看门狗续期后锁仍被持有
+			time.Sleep(1500 * time.Millisecond)
+			return "inner"
+		}, op1)
+		ntools.TestErrPainic(t, "测试 TestRedisMutexReentrant 重入", err)
+		return inner
+	}, op1, op2)
+	ntools.TestErrPainic(t, "测试 TestRedisMutexReentrant", err)
+	ntools.TestEq(t, "TestRedisMutexReentrant", "inner", result)
+	ntools.TestEq(t, "TestRedisMutexReentrant 释放后不存在", false, redisService.ExistWithoutErr("TestRedisMutexReentrant"))
+}
+
+func TestRedisMutexLockCtx(t *testing.T) {
+	holder := rediscache.RedisNewMutex("TestRedisMutexLockCtx", "holder", redisService)
+	if !holder.RedisLock() {
+		ntools.TestErrPanicMsg(t, "TestRedisMutexLockCtx 获取锁失败")
+	}
+	defer holder.RedisReleseLock()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err := redisService.LockRunCtx(ctx, "TestRedisMutexLockCtx", "waiter", 5, func() any { return nil })
+	ntools.TestErrNotNil(t, "TestRedisMutexLockCtx 等待超时", err)
+}
+
+func TestRedisClusterSlot(t *testing.T) {
+	ntools.TestEq(t, "TestRedisClusterSlot foo", 12182, rediscache.RedisClusterSlot("foo"))
+	ntools.TestEq(t, "TestRedisClusterSlot HashTag", rediscache.RedisClusterSlot("user1000"), rediscache.RedisClusterSlot("{user1000}.following"))
+}
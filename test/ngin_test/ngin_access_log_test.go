@@ -0,0 +1,79 @@
+package ngintest
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/njson"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+func TestNLogMasker(t *testing.T) {
+	masker := ngin.NewNLogMasker([]string{"password", "idCard"}, []string{"data.phone", "*.nick"})
+	masked := masker.Mask([]byte(`{"Password":"123456","user":{"idCard":"510101199001011234"},"data":[{"phone":"13800138000","name":"a"}],"phone":"keep","user2":{"nick":"abcdefgh"}}`))
+	ntools.TestEq(t, "TestNLogMasker JSON", `{"Password":"1****6","data":[{"name":"a","phone":"13****00"}],"phone":"keep","user":{"idCard":"5101****1234"},"user2":{"nick":"ab****gh"}}`, masked)
+	ntools.TestEq(t, "TestNLogMasker 非JSON", `{"password": "****", "idCard":"****"`, masker.Mask([]byte(`{"password": "a\"b", "idCard":123`)))
+}
+
+func TestNGinAccessLog(t *testing.T) {
+	logBuf := &bytes.Buffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logBuf, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.LoggerHandlerFuncWithConf(&nyaml.YamlConfAccessLog{
+		ShowReqBody:  true,
+		ShowRespBody: true,
+		MaxBodySize:  200,
+		MaskKeys:     ngin.DefaultLogMaskKeys,
+	}))
+	nGin.POST("/user/:id", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp(map[string]string{"mobile": "13800138000"}))
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/user/1?a=b", strings.NewReader(`{"userName":"niexq","password":"secret123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	nGin.GinEngine.ServeHTTP(w, req)
+
+	record, err := njson.Str2Obj[map[string]any](strings.TrimSpace(logBuf.String()))
+	ntools.TestErrPainic(t, "TestNGinAccessLog 解析日志", err)
+	ntools.TestEq(t, "TestNGinAccessLog route", "/user/:id", (*record)["route"])
+	ntools.TestEq(t, "TestNGinAccessLog status", float64(200), (*record)["status"])
+	ntools.TestEq(t, "TestNGinAccessLog query", "a=b", (*record)["query"])
+	ntools.TestEq(t, "TestNGinAccessLog req_body", `{"password":"se****23","userName":"niexq"}`, (*record)["req_body"])
+	ntools.TestStrContains(t, "TestNGinAccessLog resp_body", `"mobile":"13****00"`, (*record)["resp_body"].(string))
+	ntools.TestStrContains(t, "TestNGinAccessLog 响应不受影响", `"mobile":"13800138000"`, w.Body.String())
+}
+
+func TestNGinAccessLogEncodedResp(t *testing.T) {
+	logBuf := &bytes.Buffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logBuf, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.LoggerHandlerFuncWithConf(&nyaml.YamlConfAccessLog{ShowRespBody: true}))
+	nGin.GET("/gzip", func(ctx *gin.Context) {
+		ctx.Header("Content-Encoding", "gzip")
+		ctx.Data(http.StatusOK, "text/plain", []byte("gzip-body"))
+	})
+	nGin.GET("/endn", func(ctx *gin.Context) {
+		ctx.Header(ngin.EndnHeaderResp, "1")
+		ctx.Data(http.StatusOK, "text/plain", []byte("endn-body"))
+	})
+	for _, path := range []string{"/gzip", "/endn"} {
+		logBuf.Reset()
+		nGin.GinEngine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		record, err := njson.Str2Obj[map[string]any](strings.TrimSpace(logBuf.String()))
+		ntools.TestErrPainic(t, "TestNGinAccessLogEncodedResp 解析日志"+path, err)
+		ntools.TestEq(t, "TestNGinAccessLogEncodedResp resp_body"+path, "", (*record)["resp_body"])
+	}
+}
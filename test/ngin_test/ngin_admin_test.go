@@ -0,0 +1,67 @@
+package ngintest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+func TestNGinAdmin(t *testing.T) {
+	nGin := ngin.NewNGin()
+	admin := nGin.RegisterAdmin(nGin.Group("/admin"), &nyaml.YamlConfAdmin{
+		AllowCidrs:   []string{"10.0.0.0/8"},
+		Token:        "admin-secret",
+		ReadyTimeout: 1,
+	})
+	admin.AddReadyCheck("db", func(ctx context.Context) error { return nil })
+
+	doGet := func(path, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	ntools.TestStrContains(t, "TestNGinAdmin IP允许", `"status":"UP"`, doGet("/admin/health", "10.1.1.1:1000", nil).Body.String())
+	ntools.TestStrContains(t, "TestNGinAdmin 拒绝", `"code":9001`, doGet("/admin/health", "8.8.8.8:1000", nil).Body.String())
+	ntools.TestStrContains(t, "TestNGinAdmin 伪造XFF", `"code":9001`, doGet("/admin/debug/pprof/cmdline", "8.8.8.8:1000", map[string]string{"X-Forwarded-For": "10.1.1.1"}).Body.String())
+	ntools.TestStrContains(t, "TestNGinAdmin 伪造X-Real-Ip", `"code":9001`, doGet("/admin/health", "8.8.8.8:1000", map[string]string{"X-Real-Ip": "10.1.1.1"}).Body.String())
+	ntools.TestStrContains(t, "TestNGinAdmin Token允许", `"status":"UP"`, doGet("/admin/health", "8.8.8.8:1000", map[string]string{"Admin-Token": "admin-secret"}).Body.String())
+	ntools.TestStrContains(t, "TestNGinAdmin Bearer", `"code":0`, doGet("/admin/routes", "8.8.8.8:1000", map[string]string{"Authorization": "Bearer admin-secret"}).Body.String())
+
+	w := doGet("/admin/ready", "10.1.1.1:1000", nil)
+	ntools.TestEq(t, "TestNGinAdmin 就绪", http.StatusOK, w.Code)
+	ntools.TestStrContains(t, "TestNGinAdmin 就绪内容", `"name":"db","ok":true`, w.Body.String())
+
+	admin.AddReadyCheck("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+	admin.AddReadyCheck("mq", func(ctx context.Context) error {
+		time.Sleep(3 * time.Second)
+		return nil
+	})
+	start := time.Now()
+	w = doGet("/admin/ready", "10.1.1.1:1000", nil)
+	ntools.TestEq(t, "TestNGinAdmin 未就绪", http.StatusServiceUnavailable, w.Code)
+	ntools.TestStrContains(t, "TestNGinAdmin 检查失败", `"err":"connection refused"`, w.Body.String())
+	ntools.TestStrContains(t, "TestNGinAdmin 检查超时", `"err":"context deadline exceeded"`, w.Body.String())
+	ntools.TestEq(t, "TestNGinAdmin 超时不等待", true, time.Since(start) < 2*time.Second)
+
+	ntools.TestStrContains(t, "TestNGinAdmin 路由列表", `"path":"/admin/ready"`, doGet("/admin/routes", "10.1.1.1:1000", nil).Body.String())
+	ntools.TestStrContains(t, "TestNGinAdmin pprof", "goroutine", doGet("/admin/debug/pprof/goroutine?debug=1", "10.1.1.1:1000", nil).Body.String())
+
+	defer func() {
+		ntools.TestStrContains(t, "TestNGinAdmin 未配置", "管理接口需配置allowCidrs或token", fmt.Sprint(recover()))
+	}()
+	nGin.RegisterAdmin(nGin.Group("/admin2"), &nyaml.YamlConfAdmin{})
+}
@@ -0,0 +1,70 @@
+package ngintest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ncache"
+	"github.com/niexqc/nlibs/ndnen"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestNGinJwt(t *testing.T) {
+	principal := &ngin.NGinPrincipal{UserId: "u1", UserName: "tom", Roles: []string{"admin"}}
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	sm2Pri, _ := ndnen.Sm2GenKeyPair()
+	for _, jwt := range []*ngin.NJwt{ngin.NewJwtHmac([]byte("secret")), ngin.NewJwtRsa(rsaKey, nil), ngin.NewJwtSm2(sm2Pri, nil)} {
+		token, err := jwt.Sign(principal, time.Minute)
+		ntools.TestErrPainic(t, "TestNGinJwt 签发", err)
+		verified, err := jwt.Verify(token)
+		ntools.TestErrPainic(t, "TestNGinJwt 验证", err)
+		ntools.TestEq(t, "TestNGinJwt 用户", "tom", verified.UserName)
+		_, err = jwt.Verify(token[:len(token)-4] + "AAAA")
+		ntools.TestErrNotNil(t, "TestNGinJwt 篡改", err)
+		expired, _ := jwt.Sign(principal, -time.Minute)
+		_, err = jwt.Verify(expired)
+		ntools.TestErrNotNil(t, "TestNGinJwt 过期", err)
+	}
+	hsToken, _ := ngin.NewJwtHmac([]byte("secret")).Sign(principal, time.Minute)
+	_, err := ngin.NewJwtRsa(rsaKey, nil).Verify(hsToken)
+	ntools.TestErrNotNil(t, "TestNGinJwt 算法不一致", err)
+}
+
+func TestNGinAuth(t *testing.T) {
+	verifier := ngin.NewCacheTokenVerifier(ncache.NewMemCacheService(time.Minute), "token:", 60, true)
+	adminToken, _ := verifier.Issue(&ngin.NGinPrincipal{UserId: "1", Roles: []string{"admin"}, Perms: []string{"user:del"}})
+	userToken, _ := verifier.Issue(&ngin.NGinPrincipal{UserId: "2", Roles: []string{"user"}})
+
+	nGin := ngin.NewNGin()
+	api := nGin.Group("/api", ngin.AuthHandlerFunc(verifier))
+	api.GET("/me", func(ctx *gin.Context) {
+		principal, _ := ngin.GetPrincipal(ctx)
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp(principal.UserId))
+	})
+	api.GET("/admin", ngin.RequireRoles("admin"), ngin.RequirePerms("user:del"), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("ok"))
+	})
+
+	doGet := func(path, token string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	ntools.TestStrContains(t, "TestNGinAuth 未登录", `"code":9000`, doGet("/api/me", ""))
+	ntools.TestStrContains(t, "TestNGinAuth 错误Token", `"code":9000`, doGet("/api/me", "abc"))
+	ntools.TestStrContains(t, "TestNGinAuth 登录", `"data":"2"`, doGet("/api/me", userToken))
+	ntools.TestStrContains(t, "TestNGinAuth 无权限", `"code":9001`, doGet("/api/admin", userToken))
+	ntools.TestStrContains(t, "TestNGinAuth 有权限", `"data":"ok"`, doGet("/api/admin", adminToken))
+	verifier.Revoke(adminToken)
+	ntools.TestStrContains(t, "TestNGinAuth 退出", `"code":9000`, doGet("/api/admin", adminToken))
+}
@@ -0,0 +1,67 @@
+package ngintest
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestNGinCompressETag(t *testing.T) {
+	staticDir := t.TempDir()
+	os.WriteFile(filepath.Join(staticDir, "a.txt"), []byte("static file"), 0644)
+
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.CompressHandlerFunc(1024), ngin.ETagHandlerFunc())
+	nGin.Static("/static", staticDir)
+	nGin.GET("/list", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp(strings.Repeat("数据", 1000)))
+	})
+	nGin.GET("/small", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("ok"))
+	})
+
+	doGet := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := doGet("/list", map[string]string{"Accept-Encoding": "gzip, deflate"})
+	ntools.TestEq(t, "TestNGinCompressETag gzip", "gzip", w.Header().Get("Content-Encoding"))
+	gr, err := gzip.NewReader(w.Body)
+	ntools.TestErrPainic(t, "TestNGinCompressETag gzip读取", err)
+	body, _ := io.ReadAll(gr)
+	ntools.TestStrContains(t, "TestNGinCompressETag gzip内容", `"code":0`, string(body))
+
+	w = doGet("/list", map[string]string{"Accept-Encoding": "br;q=1, gzip;q=0.5"})
+	ntools.TestEq(t, "TestNGinCompressETag br", "br", w.Header().Get("Content-Encoding"))
+	body, _ = io.ReadAll(brotli.NewReader(w.Body))
+	ntools.TestStrContains(t, "TestNGinCompressETag br内容", `"code":0`, string(body))
+
+	w = doGet("/small", map[string]string{"Accept-Encoding": "gzip"})
+	ntools.TestEq(t, "TestNGinCompressETag 小于minSize", "", w.Header().Get("Content-Encoding"))
+	etag := w.Header().Get("ETag")
+	ntools.TestEq(t, "TestNGinCompressETag 弱ETag", true, strings.HasPrefix(etag, `W/"`))
+	w = doGet("/small", map[string]string{"If-None-Match": etag})
+	ntools.TestEq(t, "TestNGinCompressETag 304", http.StatusNotModified, w.Code)
+	ntools.TestEq(t, "TestNGinCompressETag 304无内容", 0, w.Body.Len())
+
+	w = doGet("/static/a.txt", nil)
+	ntools.TestEq(t, "TestNGinCompressETag 静态文件", "static file", w.Body.String())
+	w = doGet("/static/a.txt", map[string]string{"If-None-Match": w.Header().Get("ETag")})
+	ntools.TestEq(t, "TestNGinCompressETag 静态文件304", http.StatusNotModified, w.Code)
+}
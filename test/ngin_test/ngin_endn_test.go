@@ -0,0 +1,77 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ndnen"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+func TestNGinEndn(t *testing.T) {
+	pri, pub := ndnen.Sm2GenKeyPair()
+	priHex, pubHex := ndnen.Sm2Key2Hex(pri, pub)
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.EndnHandlerFunc(&nyaml.YamlConfEndnKey{Sm2HexPubKey: pubHex, Sm2HexPriKey: priHex}, true))
+	ngin.TypedPOST(nGin, "/api/save", "保存", func(ctx *gin.Context, req *typedUserReq) (*typedUserResp, error) {
+		return &typedUserResp{Greeting: "hi " + req.Name}, nil
+	})
+
+	hexKey, hexIv := ndnen.Sm4GenHexKeyIv()
+	doPost := func(endnKey, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/save", strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/plain")
+		if endnKey != "" {
+			req.Header.Set(ngin.EndnHeaderKey, endnKey)
+		}
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	encBody, err := ngin.EndnEncryptBody(hexKey, hexIv, []byte(`{"name":"tom","age":18}`))
+	ntools.TestErrPainic(t, "TestNGinEndn 加密请求", err)
+	w := doPost(ngin.EndnSessionKeyEncrypt(pub, hexKey, hexIv), string(encBody))
+	ntools.TestEq(t, "TestNGinEndn 响应头", "1", w.Header().Get(ngin.EndnHeaderResp))
+	respIv := w.Header().Get(ngin.EndnHeaderIv)
+	ntools.TestEq(t, "TestNGinEndn 响应IV与请求不同", true, respIv != "" && respIv != hexIv)
+	respBody, err := ngin.EndnDecryptBody(hexKey, respIv, w.Body.Bytes())
+	ntools.TestErrPainic(t, "TestNGinEndn 解密响应", err)
+	ntools.TestStrContains(t, "TestNGinEndn 成功", `"greeting":"hi tom"`, string(respBody))
+
+	ntools.TestStrContains(t, "TestNGinEndn 未加密", `"code":4060`, doPost("", `{"name":"tom","age":18}`).Body.String())
+	ntools.TestStrContains(t, "TestNGinEndn 密钥错误", "会话密钥解密失败", doPost("0011", string(encBody)).Body.String())
+	otherKey, otherIv := ndnen.Sm4GenHexKeyIv()
+	ntools.TestStrContains(t, "TestNGinEndn 密文错误", `"code":4060`, doPost(ngin.EndnSessionKeyEncrypt(pub, otherKey, otherIv), string(encBody)).Body.String())
+}
+
+func TestNGinEndnFlush(t *testing.T) {
+	pri, pub := ndnen.Sm2GenKeyPair()
+	priHex, pubHex := ndnen.Sm2Key2Hex(pri, pub)
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.EndnHandlerFunc(&nyaml.YamlConfEndnKey{Sm2HexPubKey: pubHex, Sm2HexPriKey: priHex}, true))
+	nGin.POST("/api/flush", func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+		ctx.Writer.WriteHeaderNow()
+		ctx.Writer.WriteString(`{"name":"tom"}`)
+		ctx.Writer.Flush()
+	})
+
+	hexKey, hexIv := ndnen.Sm4GenHexKeyIv()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/flush", nil)
+	req.Header.Set(ngin.EndnHeaderKey, ngin.EndnSessionKeyEncrypt(pub, hexKey, hexIv))
+	nGin.GinEngine.ServeHTTP(w, req)
+
+	ntools.TestEq(t, "TestNGinEndnFlush 状态码", http.StatusCreated, w.Code)
+	ntools.TestEq(t, "TestNGinEndnFlush 响应头", "1", w.Header().Get(ngin.EndnHeaderResp))
+	ntools.TestStrContains(t, "TestNGinEndnFlush Content-Type", "text/plain", w.Header().Get("Content-Type"))
+	respBody, err := ngin.EndnDecryptBody(hexKey, w.Header().Get(ngin.EndnHeaderIv), w.Body.Bytes())
+	ntools.TestErrPainic(t, "TestNGinEndnFlush 解密响应", err)
+	ntools.TestEq(t, "TestNGinEndnFlush 响应体", `{"name":"tom"}`, string(respBody))
+}
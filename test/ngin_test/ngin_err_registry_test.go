@@ -0,0 +1,75 @@
+package ngintest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/nerror"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+var errUserNotFound = errors.New("用户不存在")
+
+type bizErr struct {
+	BizCode int
+}
+
+func (e *bizErr) Error() string {
+	return fmt.Sprintf("业务错误%d", e.BizCode)
+}
+
+func TestNGinErrRegistry(t *testing.T) {
+	registry := ngin.NewNErrRegistry()
+	registry.RegisterSentinel(errUserNotFound, ngin.NErrMapping{HttpStatus: http.StatusNotFound, Code: 4040, Msg: "查询失败:{err}"})
+	ngin.RegisterErrType[*bizErr](registry, ngin.NErrMapping{Code: 5000, MsgFunc: func(err error) string {
+		return fmt.Sprintf("业务码%d", err.(*bizErr).BizCode)
+	}})
+
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.RecoveryHandlerFuncWithRegistry(registry))
+	ngin.TypedGET(nGin, "/user", "查询用户", func(ctx *gin.Context, req *ngin.EmptyObj) (*typedUserResp, error) {
+		return nil, fmt.Errorf("查询用户: %w", errUserNotFound)
+	})
+	nGin.GET("/biz", func(ctx *gin.Context) {
+		panic(nerror.NewRunTimeErrorWithError("处理失败", &bizErr{BizCode: 7}))
+	})
+	nGin.GET("/biz2", func(ctx *gin.Context) {
+		ctx.Error(errors.Join(errors.New("其他"), &bizErr{BizCode: 8}))
+	})
+	nGin.GET("/runtime", func(ctx *gin.Context) {
+		panic(nerror.NewRunTimeError("运行时异常"))
+	})
+	nGin.GET("/unknown", func(ctx *gin.Context) {
+		panic("未知")
+	})
+
+	doGet := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		nGin.GinEngine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	w := doGet("/user")
+	ntools.TestEq(t, "TestNGinErrRegistry 哨兵状态码", http.StatusNotFound, w.Code)
+	ntools.TestStrContains(t, "TestNGinErrRegistry 哨兵", `"msg":"查询失败:查询用户: 用户不存在"`, w.Body.String())
+	// 外层的RunTimeErr优先于内层的bizErr
+	ntools.TestStrContains(t, "TestNGinErrRegistry 外层优先", `"code":3000`, doGet("/biz").Body.String())
+	ntools.TestStrContains(t, "TestNGinErrRegistry ctx.Error", `"msg":"业务码8"`, doGet("/biz2").Body.String())
+	ntools.TestStrContains(t, "TestNGinErrRegistry 运行时异常", `"code":2000`, doGet("/runtime").Body.String())
+	ntools.TestStrContains(t, "TestNGinErrRegistry 未知", `"code":9999`, doGet("/unknown").Body.String())
+}
+
+func TestNGinErrRegistryRunTimeSrcErr(t *testing.T) {
+	registry := &ngin.NErrRegistry{}
+	registry.RegisterSentinel(errUserNotFound, ngin.NErrMapping{Code: 4040})
+	err := fmt.Errorf("查询: %w", nerror.NewRunTimeErrorWithError("查询失败", errUserNotFound))
+	mapping, matched := registry.Lookup(err)
+	ntools.TestEq(t, "TestNGinErrRegistryRunTimeSrcErr 遍历SrcErr", 4040, mapping.Code)
+	ntools.TestEq(t, "TestNGinErrRegistryRunTimeSrcErr 匹配到的错误", errUserNotFound, matched)
+	// RunTimeErr不参与errors.Is/errors.As
+	ntools.TestEq(t, "TestNGinErrRegistryRunTimeSrcErr errors.Is", false, errors.Is(err, errUserNotFound))
+}
@@ -0,0 +1,76 @@
+package ngintest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+type typedUserReq struct {
+	Name string        `json:"name" zhdesc:"姓名" binding:"required,max=10"`
+	Age  ngin.ReqVoInt `json:"age" zhdesc:"年龄" binding:"required,gte=1"`
+}
+
+type typedUserResp struct {
+	Greeting string `json:"greeting"`
+}
+
+func TestNGinGroupTypedHandler(t *testing.T) {
+	nGin := ngin.NewNGin()
+	api := nGin.Group("/api")
+	user := api.Group("/user")
+	user.PUT("/save", ngin.Handle(nGin.NValider, func(ctx *gin.Context, req *typedUserReq) (*typedUserResp, error) {
+		if req.Name == "err" {
+			return nil, errors.New("保存失败")
+		}
+		return &typedUserResp{Greeting: "hi " + req.Name}, nil
+	}))
+
+	doPut := func(body string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/user/save", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	ntools.TestStrContains(t, "TestNGinGroupTypedHandler 成功", `"greeting":"hi tom"`, doPut(`{"name":"tom","age":"18"}`))
+	ntools.TestStrContains(t, "TestNGinGroupTypedHandler 验证失败", `"code":1000`, doPut(`{"name":"tom"}`))
+	ntools.TestStrContains(t, "TestNGinGroupTypedHandler 验证失败描述", "年龄[age]", doPut(`{"name":"tom"}`))
+	ntools.TestStrContains(t, "TestNGinGroupTypedHandler 处理失败", "保存失败", doPut(`{"name":"err","age":1}`))
+}
+
+type typedUserQuery struct {
+	Name string `form:"name" json:"name" zhdesc:"姓名" binding:"required,max=10"`
+	Age  int    `form:"age" json:"age" zhdesc:"年龄" binding:"gte=0"`
+}
+
+func TestNGinGroupTypedHandlerQuery(t *testing.T) {
+	nGin := ngin.NewNGin()
+	user := nGin.Group("/api/user")
+	handler := ngin.Handle(nGin.NValider, func(ctx *gin.Context, req *typedUserQuery) (*typedUserResp, error) {
+		ctx.Header("X-Greeting", fmt.Sprintf("hi %s %d", req.Name, req.Age))
+		return &typedUserResp{Greeting: fmt.Sprintf("hi %s %d", req.Name, req.Age)}, nil
+	})
+	user.GET("/get", handler)
+	user.DELETE("/del", handler)
+	user.HEAD("/head", handler)
+
+	doReq := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		nGin.GinEngine.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+
+	ntools.TestStrContains(t, "TestNGinGroupTypedHandlerQuery GET", `"greeting":"hi tom 18"`, doReq(http.MethodGet, "/api/user/get?name=tom&age=18").Body.String())
+	ntools.TestStrContains(t, "TestNGinGroupTypedHandlerQuery GET验证失败", "姓名[name]", doReq(http.MethodGet, "/api/user/get?age=18").Body.String())
+	ntools.TestStrContains(t, "TestNGinGroupTypedHandlerQuery DELETE", `"greeting":"hi tom 18"`, doReq(http.MethodDelete, "/api/user/del?name=tom&age=18").Body.String())
+	ntools.TestEq(t, "TestNGinGroupTypedHandlerQuery HEAD", "hi tom 18", doReq(http.MethodHead, "/api/user/head?name=tom&age=18").Header().Get("X-Greeting"))
+}
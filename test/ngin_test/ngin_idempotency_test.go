@@ -0,0 +1,95 @@
+package ngintest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ncache"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestNGinIdempotency(t *testing.T) {
+	var orderNo atomic.Int64
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.IdempotencyHandlerFunc(ncache.NewMemCacheService(time.Minute), "idem:", 60, 10))
+	nGin.POST("/order", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp(orderNo.Add(1)))
+	})
+	nGin.POST("/slow", func(ctx *gin.Context) {
+		close(started)
+		<-release
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("ok"))
+	})
+	nGin.POST("/fail", func(ctx *gin.Context) {
+		orderNo.Add(1)
+		ctx.JSON(http.StatusOK, ngin.NewNoBaseResp(ngin.RespCode_RunTime_Err, "失败"))
+	})
+	doPost := func(path, key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	first := doPost("/order", "k1", `{"a":1}`)
+	second := doPost("/order", "k1", `{"a":1}`)
+	ntools.TestEq(t, "TestNGinIdempotency 重放响应", first.Body.String(), second.Body.String())
+	ntools.TestEq(t, "TestNGinIdempotency 重放响应头", "true", second.Header().Get(ngin.IdempotencyReplayedHeader))
+	ntools.TestEq(t, "TestNGinIdempotency 只执行一次", int64(1), orderNo.Load())
+	ntools.TestStrContains(t, "TestNGinIdempotency 不同请求", `"code":1000`, doPost("/order", "k1", `{"a":2}`).Body.String())
+
+	doPost("/fail", "k2", "")
+	doPost("/fail", "k2", "")
+	ntools.TestEq(t, "TestNGinIdempotency 失败后可重试", int64(3), orderNo.Load())
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- doPost("/slow", "k3", "")
+	}()
+	<-started
+	ntools.TestStrContains(t, "TestNGinIdempotency 处理中", `"code":4090`, doPost("/slow", "k3", "").Body.String())
+	close(release)
+	ntools.TestStrContains(t, "TestNGinIdempotency 首次请求完成", `"code":0`, (<-done).Body.String())
+}
+
+// 模拟不可用的缓存
+type idemErrCache struct{}
+
+func (idemErrCache) TryPutNxExStr(key string, val string, sencond int) (bool, error) {
+	return false, errors.New("连接失败")
+}
+func (idemErrCache) GetStr(key string) (string, error) { return "", errors.New("连接失败") }
+func (idemErrCache) PutExStr(key string, val string, sencond int) error {
+	return errors.New("连接失败")
+}
+func (idemErrCache) ClearKey(key string) error { return errors.New("连接失败") }
+
+func TestNGinIdempotencyCacheErr(t *testing.T) {
+	var orderNo atomic.Int64
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.IdempotencyHandlerFunc(idemErrCache{}, "idem:", 60, 10))
+	nGin.POST("/order", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp(orderNo.Add(1)))
+	})
+	doPost := func() string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"a":1}`))
+		req.Header.Set("Idempotency-Key", "k1")
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	ntools.TestStrContains(t, "TestNGinIdempotencyCacheErr 放行", `"code":0`, doPost())
+	ntools.TestStrContains(t, "TestNGinIdempotencyCacheErr 再次放行", `"code":0`, doPost())
+	ntools.TestEq(t, "TestNGinIdempotencyCacheErr 均已执行", int64(2), orderNo.Load())
+}
@@ -0,0 +1,50 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestNGinMetrics(t *testing.T) {
+	nGin := ngin.NewNGinWithMaxConcurrent(1, 200, 100, ngin.NewNValider("json", "zhdesc"))
+	nGin.EnableMetrics("/metrics", "app")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	nGin.GET("/user/:id", func(ctx *gin.Context) {
+		if ctx.Param("id") == "slow" {
+			close(started)
+			<-release
+		}
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp(ctx.Param("id")))
+	})
+	doGet := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		nGin.GinEngine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	done := make(chan struct{})
+	go func() {
+		doGet("/user/slow")
+		close(done)
+	}()
+	<-started
+	ntools.TestEq(t, "TestNGinMetrics 超过并发", http.StatusTooManyRequests, doGet("/user/2").Code)
+	close(release)
+	<-done
+	doGet("/user/1")
+	doGet("/none")
+
+	body := doGet("/metrics").Body.String()
+	ntools.TestStrContains(t, "TestNGinMetrics 请求数", `app_http_requests_total{method="GET",route="/user/:id",status="200"} 2`, body)
+	ntools.TestStrContains(t, "TestNGinMetrics 未匹配", `route="unmatched",status="404"`, body)
+	ntools.TestStrContains(t, "TestNGinMetrics 耗时", `app_http_request_duration_seconds_bucket{method="GET",route="/user/:id",status="200",le="+Inf"} 2`, body)
+	ntools.TestStrContains(t, "TestNGinMetrics 处理中", "app_http_requests_in_flight 1", body)
+	ntools.TestStrContains(t, "TestNGinMetrics 拒绝", "app_http_max_concurrent_rejected_total 1", body)
+	ntools.TestStrContains(t, "TestNGinMetrics 协程", "# TYPE go_goroutines gauge", body)
+}
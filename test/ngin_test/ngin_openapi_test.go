@@ -0,0 +1,101 @@
+package ngintest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ndb/sqlext"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+type openApiUserQuery struct {
+	ngin.BaseReqPage
+	Name   string `form:"name" json:"name" zhdesc:"姓名" binding:"max=20"`
+	Status int    `form:"status" json:"status" zhdesc:"状态" binding:"oneof=1 2"`
+}
+
+type openApiUserVo struct {
+	Id       int64              `json:"id" zhdesc:"主键"`
+	Name     string             `json:"name" zhdesc:"姓名"`
+	Birthday sqlext.NullTime    `json:"birthday" zhdesc:"生日"`
+	Tags     []string           `json:"tags" zhdesc:"标签" binding:"max=5"`
+	Children []*openApiUserVo   `json:"children" zhdesc:"下级"`
+	Ext      map[string]float64 `json:"ext"`
+}
+
+func TestNGinOpenApi(t *testing.T) {
+	nGin := ngin.NewNGin()
+	user := nGin.Group("/api/user")
+	ngin.TypedGET(user, "/list", "用户列表", func(ctx *gin.Context, req *openApiUserQuery) (*[]openApiUserVo, error) {
+		return &[]openApiUserVo{}, nil
+	})
+	ngin.TypedPOST(user, "/save", "保存用户", func(ctx *gin.Context, req *typedUserReq) (*typedUserResp, error) {
+		return &typedUserResp{Greeting: req.Name}, nil
+	})
+	ngin.TypedDELETE(user, "/:id", "删除用户", func(ctx *gin.Context, req *ngin.EmptyObj) (*ngin.EmptyObj, error) {
+		return nil, nil
+	})
+	nGin.ServeOpenApi("/openapi.json", "测试接口", "1.0.0")
+	nGin.ServeOpenApi("/openapi.yaml", "测试接口", "1.0.0")
+
+	w := httptest.NewRecorder()
+	nGin.GinEngine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	doc := map[string]any{}
+	ntools.TestErrPainic(t, "TestNGinOpenApi 解析文档", json.Unmarshal(w.Body.Bytes(), &doc))
+	ntools.TestEq(t, "TestNGinOpenApi 版本", "3.0.3", doc["openapi"])
+
+	paths := doc["paths"].(map[string]any)
+	ntools.TestEq(t, "TestNGinOpenApi 路径数", 3, len(paths))
+	ntools.TestEq(t, "TestNGinOpenApi 路径参数", true, paths["/api/user/{id}"] != nil)
+
+	body := w.Body.String()
+	ntools.TestStrContains(t, "TestNGinOpenApi 描述", `"description":"姓名"`, body)
+	ntools.TestStrContains(t, "TestNGinOpenApi 枚举", `"enum":[1,2]`, body)
+	ntools.TestStrContains(t, "TestNGinOpenApi 分页参数", `"name":"PageNo"`, body)
+	ntools.TestStrContains(t, "TestNGinOpenApi 必填", `"required":["name","age"]`, body)
+	ntools.TestStrContains(t, "TestNGinOpenApi 长度", `"maxLength":10`, body)
+	ntools.TestStrContains(t, "TestNGinOpenApi 数组", `"maxItems":5`, body)
+	ntools.TestStrContains(t, "TestNGinOpenApi 引用", `"$ref":"#/components/schemas/openApiUserVo"`, body)
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	baseResp := schemas["BaseResp"].(map[string]any)["properties"].(map[string]any)
+	ntools.TestEq(t, "TestNGinOpenApi swaggerignore", nil, baseResp["data"])
+
+	w = httptest.NewRecorder()
+	nGin.GinEngine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	ntools.TestStrContains(t, "TestNGinOpenApi YAML", "openapi: 3.0.3", w.Body.String())
+}
+
+func TestNGinOpenApiReqVoType(t *testing.T) {
+	nGin := ngin.NewNGin()
+	ngin.RegisterReqVoEnum(nGin.NValider, voOrderStatus("NEW"), voOrderStatus("PAID"))
+	ngin.RegisterReqVoEnum(nGin.NValider, 1, 2, 3)
+	ngin.TypedPOST(nGin, "/vo", "类型", func(ctx *gin.Context, req *voTypeReq) (*ngin.EmptyObj, error) {
+		return nil, nil
+	})
+	nGin.ServeOpenApi("/openapi.json", "测试接口", "1.0.0")
+
+	w := httptest.NewRecorder()
+	nGin.GinEngine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	doc := map[string]any{}
+	ntools.TestErrPainic(t, "TestNGinOpenApiReqVoType 解析文档", json.Unmarshal(w.Body.Bytes(), &doc))
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	props := schemas["voTypeReq"].(map[string]any)["properties"].(map[string]any)
+
+	start := props["start"].(map[string]any)
+	ntools.TestEq(t, "TestNGinOpenApiReqVoType 时间类型", "string", start["type"])
+	ntools.TestEq(t, "TestNGinOpenApiReqVoType 时间示例", "2006-01-02 15:04:05", start["example"])
+	ntools.TestEq(t, "TestNGinOpenApiReqVoType 时间可为null", true, start["nullable"])
+
+	status := props["status"].(map[string]any)
+	ntools.TestEq(t, "TestNGinOpenApiReqVoType 字符串枚举类型", "string", status["type"])
+	ntools.TestEq(t, "TestNGinOpenApiReqVoType 字符串枚举值", "[NEW PAID]", fmt.Sprint(status["enum"]))
+	priority := props["priority"].(map[string]any)
+	ntools.TestEq(t, "TestNGinOpenApiReqVoType 数字枚举类型", "integer", priority["type"])
+	ntools.TestEq(t, "TestNGinOpenApiReqVoType 数字枚举值", "[1 2 3]", fmt.Sprint(priority["enum"]))
+}
@@ -0,0 +1,49 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ncache"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestRateLimitHandlerFunc(t *testing.T) {
+	memCacheService := ncache.NewMemCacheService(time.Minute)
+	limiter := memCacheService.NewSlidingWindowLimiter("rl:", 2, time.Second)
+
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.RateLimitHandlerFunc(limiter, ngin.RateLimitKeyByRoute))
+	nGin.GET("/ping", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("pong"))
+	})
+
+	codes := []int{}
+	var lastResp *httptest.ResponseRecorder
+	for range 3 {
+		lastResp = httptest.NewRecorder()
+		nGin.GinEngine.ServeHTTP(lastResp, httptest.NewRequest(http.MethodGet, "/ping", nil))
+		codes = append(codes, lastResp.Code)
+	}
+	ntools.TestEq(t, "TestRateLimitHandlerFunc 第1次", http.StatusOK, codes[0])
+	ntools.TestEq(t, "TestRateLimitHandlerFunc 第2次", http.StatusOK, codes[1])
+	ntools.TestEq(t, "TestRateLimitHandlerFunc 第3次", http.StatusTooManyRequests, codes[2])
+	ntools.TestEq(t, "TestRateLimitHandlerFunc Retry-After", "1", lastResp.Header().Get("Retry-After"))
+	ntools.TestStrContains(t, "TestRateLimitHandlerFunc 响应码", "4290", lastResp.Body.String())
+}
+
+func TestMemTokenBucketAllow(t *testing.T) {
+	memCacheService := ncache.NewMemCacheService(time.Minute)
+	for range 5 {
+		allowed, _, _, err := memCacheService.TokenBucketAllow("tb", 10, 5, 1)
+		ntools.TestErrPainic(t, "TestMemTokenBucketAllow", err)
+		ntools.TestEq(t, "TestMemTokenBucketAllow 桶容量内", true, allowed)
+	}
+	allowed, _, retryAfter, _ := memCacheService.TokenBucketAllow("tb", 10, 5, 1)
+	ntools.TestEq(t, "TestMemTokenBucketAllow 超过容量", false, allowed)
+	ntools.TestEq(t, "TestMemTokenBucketAllow 等待时间", true, retryAfter > 0 && retryAfter <= 100*time.Millisecond)
+}
@@ -0,0 +1,74 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/njson"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+type voOrderStatus string
+
+type voTypeReq struct {
+	Name     ngin.ReqVoTrimString          `json:"name" form:"name" zhdesc:"名称" binding:"required,max=4"`
+	Start    ngin.ReqVoTime                `json:"start" form:"start" zhdesc:"开始时间" binding:"required"`
+	Ids      ngin.ReqVoIdList[int64]       `json:"ids" form:"ids" zhdesc:"ID列表" binding:"required,gte=1,dive,gte=1"`
+	Status   ngin.ReqVoEnum[voOrderStatus] `json:"status" form:"status" zhdesc:"状态" binding:"required,enum"`
+	Priority ngin.ReqVoEnum[int]           `json:"priority" form:"priority" zhdesc:"优先级" binding:"omitempty,enum"`
+}
+
+func TestReqVoType(t *testing.T) {
+	nGin := ngin.NewNGin()
+	ngin.RegisterReqVoEnum(nGin.NValider, voOrderStatus("NEW"), voOrderStatus("PAID"))
+	ngin.RegisterReqVoEnum(nGin.NValider, 1, 2, 3)
+	handler := ngin.Handle(nGin.NValider, func(ctx *gin.Context, req *voTypeReq) (*voTypeReq, error) {
+		return req, nil
+	})
+	nGin.POST("/vo", handler)
+	nGin.GET("/vo", handler)
+	doReq := func(method, url, body string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	body := doReq(http.MethodPost, "/vo", `{"name":"  abc ","start":"2025-03-04","ids":"1, 2,,3","status":"PAID","priority":"2"}`)
+	ntools.TestStrContains(t, "TestReqVoType JSON", `"code":0`, body)
+	ntools.TestStrContains(t, "TestReqVoType 去空白", `"name":"abc"`, body)
+	ntools.TestStrContains(t, "TestReqVoType 时间", `"start":"2025-03-04 00:00:00"`, body)
+	ntools.TestStrContains(t, "TestReqVoType ID列表", `"ids":[1,2,3]`, body)
+	ntools.TestStrContains(t, "TestReqVoType 枚举", `"status":"PAID","priority":2`, body)
+
+	body = doReq(http.MethodPost, "/vo", `{"name":"   ","start":"","ids":[0,"-1"],"status":"CLOSED","priority":5}`)
+	ntools.TestStrContains(t, "TestReqVoType required去空白", "名称[name]为必填字段", body)
+	ntools.TestStrContains(t, "TestReqVoType 时间required", "开始时间[start]为必填字段", body)
+	ntools.TestStrContains(t, "TestReqVoType ID元素", "ID列表[ids][0]必须满足条件,大于等于[1]", body)
+	ntools.TestStrContains(t, "TestReqVoType 枚举值", "状态[status]必须是[NEW PAID]中的一个", body)
+	ntools.TestStrContains(t, "TestReqVoType 数字枚举", "优先级[priority]必须是[1 2 3]中的一个", body)
+
+	body = doReq(http.MethodPost, "/vo", `{"name":"a","start":"2025-13-01","ids":"1","status":"NEW"}`)
+	ntools.TestStrContains(t, "TestReqVoType 错误时间", "错误的时间字符串: 2025-13-01", body)
+
+	body = doReq(http.MethodGet, "/vo?name=%20q%20&start=20250304101112&ids=5&ids=6&status=NEW", "")
+	ntools.TestStrContains(t, "TestReqVoType Query", `"name":"q","start":"2025-03-04 10:11:12","ids":[5,6],"status":"NEW"`, body)
+
+	vo, err := njson.Str2Obj[voTypeReq](`{"start":1741082400000,"ids":[]}`)
+	ntools.TestErrPainic(t, "TestReqVoType 毫秒时间戳", err)
+	ntools.TestEq(t, "TestReqVoType 毫秒时间戳值", int64(1741082400000), vo.Start.Value().UnixMilli())
+	ntools.TestEq(t, "TestReqVoType 空ID列表", 0, len(vo.Ids.Value()))
+	ntools.TestEq(t, "TestReqVoType RFC3339", true, parseVoTime(t, `"2025-03-04T10:00:00Z"`).Equal(time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)))
+}
+
+func parseVoTime(t *testing.T, data string) time.Time {
+	var val ngin.ReqVoTime
+	ntools.TestErrPainic(t, "parseVoTime", val.UnmarshalJSON([]byte(data)))
+	return val.Value()
+}
@@ -0,0 +1,119 @@
+package ngintest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+func TestNGinCors(t *testing.T) {
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.CorsHandlerFunc(&nyaml.YamlConfCors{
+		AllowOrigins:     []string{"https://a.com", "https://*.b.com"},
+		ExposeHeaders:    []string{"Endn-Resp"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}))
+	nGin.GET("/data", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("ok"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/data", nil)
+	req.Header.Set("Origin", "https://x.b.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "User-Token")
+	nGin.GinEngine.ServeHTTP(w, req)
+	ntools.TestEq(t, "TestNGinCors 预检", http.StatusNoContent, w.Code)
+	ntools.TestEq(t, "TestNGinCors 预检Origin", "https://x.b.com", w.Header().Get("Access-Control-Allow-Origin"))
+	ntools.TestEq(t, "TestNGinCors 预检Headers", "User-Token", w.Header().Get("Access-Control-Allow-Headers"))
+	ntools.TestEq(t, "TestNGinCors 预检MaxAge", "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("Origin", "https://a.com")
+	nGin.GinEngine.ServeHTTP(w, req)
+	ntools.TestEq(t, "TestNGinCors 普通请求", "https://a.com", w.Header().Get("Access-Control-Allow-Origin"))
+	ntools.TestEq(t, "TestNGinCors 凭证", "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	ntools.TestEq(t, "TestNGinCors Expose", "Endn-Resp", w.Header().Get("Access-Control-Expose-Headers"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodOptions, "/data", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	nGin.GinEngine.ServeHTTP(w, req)
+	ntools.TestEq(t, "TestNGinCors 来源不允许", http.StatusForbidden, w.Code)
+
+	defer func() {
+		ntools.TestStrContains(t, "TestNGinCors 全部来源与凭证", "不能开启allowCredentials", fmt.Sprint(recover()))
+	}()
+	ngin.CorsHandlerFunc(&nyaml.YamlConfCors{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestNGinSecurityHeaders(t *testing.T) {
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.SecurityHeadersHandlerFunc(&nyaml.YamlConfSecurityHeaders{HstsMaxAge: 31536000, HstsIncludeSubDomains: true, ContentSecurityPolicy: "default-src 'self'"}))
+	nGin.GET("/data", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("ok"))
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	nGin.GinEngine.ServeHTTP(w, req)
+	ntools.TestEq(t, "TestNGinSecurityHeaders nosniff", "nosniff", w.Header().Get("X-Content-Type-Options"))
+	ntools.TestEq(t, "TestNGinSecurityHeaders frame", "DENY", w.Header().Get("X-Frame-Options"))
+	ntools.TestEq(t, "TestNGinSecurityHeaders csp", "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+	ntools.TestEq(t, "TestNGinSecurityHeaders hsts", "max-age=31536000; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+}
+
+func TestNGinIpAcl(t *testing.T) {
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.IpAclHandlerFunc(&nyaml.YamlConfIpAcl{
+		AllowCidrs:     []string{"10.0.0.0/8", "192.168.1.10"},
+		DenyCidrs:      []string{"10.0.0.5"},
+		TrustedProxies: []string{"172.16.0.1"},
+	}))
+	nGin.GET("/admin", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("ok"))
+	})
+	doGet := func(remoteAddr, forwarded string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w
+	}
+	ntools.TestStrContains(t, "TestNGinIpAcl 允许", `"code":0`, doGet("10.1.2.3:1234", "").Body.String())
+	ntools.TestStrContains(t, "TestNGinIpAcl 单个IP", `"code":0`, doGet("192.168.1.10:1234", "").Body.String())
+	ntools.TestStrContains(t, "TestNGinIpAcl 禁止", `"code":9001`, doGet("10.0.0.5:1234", "").Body.String())
+	ntools.TestStrContains(t, "TestNGinIpAcl 不在允许列表", `"code":9001`, doGet("8.8.8.8:1234", "").Body.String())
+	ntools.TestStrContains(t, "TestNGinIpAcl 可信代理", `"code":0`, doGet("172.16.0.1:1234", "8.8.8.8, 10.1.2.3").Body.String())
+	ntools.TestStrContains(t, "TestNGinIpAcl 非可信代理伪造", `"code":9001`, doGet("8.8.8.8:1234", "10.1.2.3").Body.String())
+}
+
+func TestNGinIpAclWithoutProxies(t *testing.T) {
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.IpAclHandlerFunc(&nyaml.YamlConfIpAcl{AllowCidrs: []string{"127.0.0.1/32"}}))
+	nGin.GET("/admin", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp("ok"))
+	})
+	doGet := func(remoteAddr, forwarded string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwarded)
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	ntools.TestStrContains(t, "TestNGinIpAclWithoutProxies 连接IP", `"code":0`, doGet("127.0.0.1:1234", "8.8.8.8"))
+	ntools.TestStrContains(t, "TestNGinIpAclWithoutProxies 伪造XFF", `"code":9001`, doGet("8.8.8.8:1234", "127.0.0.1"))
+}
@@ -0,0 +1,77 @@
+package ngintest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestNGinGracefulShutdown(t *testing.T) {
+	nGin := ngin.NewNGin()
+	nGin.GET("/slow", func(ctx *gin.Context) {
+		time.Sleep(500 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+	hookOrder := []string{}
+	nGin.OnShutdown("log", func(ctx context.Context) error {
+		hookOrder = append(hookOrder, "log")
+		return nil
+	})
+	nGin.OnShutdown("db", func(ctx context.Context) error {
+		hookOrder = append(hookOrder, "db")
+		return nil
+	})
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- nGin.Run("127.0.0.1:18532")
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	respBody := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://127.0.0.1:18532/slow")
+		if err != nil {
+			respBody <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		respBody <- string(data)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	err := nGin.Shutdown()
+	ntools.TestErrPainic(t, "TestNGinGracefulShutdown Shutdown", err)
+	ntools.TestEq(t, "TestNGinGracefulShutdown 处理中的请求完成", "done", <-respBody)
+	ntools.TestErrPainic(t, "TestNGinGracefulShutdown Run", <-runErr)
+	ntools.TestEq(t, "TestNGinGracefulShutdown 钩子逆序执行", "db,log", strings.Join(hookOrder, ","))
+}
+
+func TestNGinShutdownWithoutConstructor(t *testing.T) {
+	nGin := &ngin.NGin{GinEngine: gin.New()}
+	called := false
+	nGin.OnShutdown("hook", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- nGin.Shutdown()
+	}()
+	select {
+	case err := <-done:
+		ntools.TestErrPainic(t, "TestNGinShutdownWithoutConstructor Shutdown", err)
+	case <-time.After(time.Second):
+		t.Fatal("TestNGinShutdownWithoutConstructor Shutdown未返回")
+	}
+	ntools.TestEq(t, "TestNGinShutdownWithoutConstructor 钩子已执行", true, called)
+	ntools.TestErrPainic(t, "TestNGinShutdownWithoutConstructor 再次调用", nGin.Shutdown())
+}
@@ -0,0 +1,101 @@
+package ngintest
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/niexqc/nlibs/ncache"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestNGinSse(t *testing.T) {
+	closed := make(chan struct{})
+	nGin := ngin.NewNGin()
+	nGin.GET("/progress", func(ctx *gin.Context) {
+		stream := ngin.NewSseStream(ctx, 20*time.Millisecond)
+		defer stream.Close()
+		stream.SendEvent(ngin.NSseEvent{Id: "1", Event: "progress", Data: map[string]int{"percent": 50}})
+		stream.Send("line1\nline2")
+		<-stream.Done()
+		close(closed)
+	})
+	server := httptest.NewServer(nGin.GinEngine)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/progress")
+	ntools.TestErrPainic(t, "TestNGinSse 请求", err)
+	ntools.TestEq(t, "TestNGinSse Content-Type", "text/event-stream; charset=utf-8", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+	lines := []string{}
+	for len(lines) < 7 {
+		line, err := reader.ReadString('\n')
+		ntools.TestErrPainic(t, "TestNGinSse 读取", err)
+		lines = append(lines, strings.TrimRight(line, "\n"))
+	}
+	ntools.TestEq(t, "TestNGinSse 事件", `id: 1|event: progress|data: {"percent":50}||data: line1|data: line2|`, strings.Join(lines, "|"))
+	line, _ := reader.ReadString('\n')
+	ntools.TestEq(t, "TestNGinSse 心跳", ": ping\n", line)
+
+	resp.Body.Close()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("TestNGinSse 客户端断开后未关闭")
+	}
+}
+
+func TestNGinWebSocket(t *testing.T) {
+	verifier := ngin.NewCacheTokenVerifier(ncache.NewMemCacheService(time.Minute), "token:", 60, false)
+	token, _ := verifier.Issue(&ngin.NGinPrincipal{UserId: "u1", Roles: []string{"admin"}})
+
+	hub := ngin.NewNWsHub(nil)
+	hub.OnMessage = func(conn *ngin.NWsConn, msgType int, data []byte) {
+		conn.Send([]byte(conn.Principal.UserId + ":" + string(data)))
+	}
+	joined := make(chan struct{}, 2)
+	nGin := ngin.NewNGin()
+	nGin.GET("/ws", ngin.AuthHandlerFuncWithQuery(verifier, "token"), hub.HandlerFunc(func(conn *ngin.NWsConn) error {
+		conn.Join("task-1")
+		joined <- struct{}{}
+		return nil
+	}))
+	server := httptest.NewServer(nGin.GinEngine)
+	defer server.Close()
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?token="
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsUrl+"bad", nil)
+	ntools.TestErrNotNil(t, "TestNGinWebSocket 未登录", err)
+	ntools.TestEq(t, "TestNGinWebSocket 未登录未升级", http.StatusOK, resp.StatusCode)
+
+	c1, _, err := websocket.DefaultDialer.Dial(wsUrl+token, nil)
+	ntools.TestErrPainic(t, "TestNGinWebSocket 连接1", err)
+	defer c1.Close()
+	c2, _, err := websocket.DefaultDialer.Dial(wsUrl+token, nil)
+	ntools.TestErrPainic(t, "TestNGinWebSocket 连接2", err)
+	<-joined
+	<-joined
+	ntools.TestEq(t, "TestNGinWebSocket 分组连接数", 2, hub.Count("task-1"))
+
+	c1.WriteMessage(websocket.TextMessage, []byte("hi"))
+	_, data, err := c1.ReadMessage()
+	ntools.TestErrPainic(t, "TestNGinWebSocket 读取回复", err)
+	ntools.TestEq(t, "TestNGinWebSocket 回复", "u1:hi", string(data))
+
+	ntools.TestEq(t, "TestNGinWebSocket 广播", 2, hub.Broadcast("task-1", []byte("50%")))
+	_, data, _ = c2.ReadMessage()
+	ntools.TestEq(t, "TestNGinWebSocket 广播内容", "50%", string(data))
+
+	c2.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Count("") != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ntools.TestEq(t, "TestNGinWebSocket 断开后移除", 1, hub.Count("task-1"))
+}
@@ -0,0 +1,54 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestNGinTraceId(t *testing.T) {
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(ntools.TraceIdHeader) + "|" + r.Header.Get(ntools.TraceParentHeader)))
+	}))
+	defer downstream.Close()
+
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.TraceIdHandlerFunc(nil))
+	nGin.GET("/call", func(ctx *gin.Context) {
+		text, err := ntools.GetHttpClientExt().GetText(downstream.URL, 3*time.Second)
+		ntools.TestErrPainic(t, "TestNGinTraceId 下游请求", err)
+		ctx.String(http.StatusOK, text)
+	})
+	doGet := func(headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/call", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	w3cTraceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	w := doGet(map[string]string{ntools.TraceParentHeader: "00-" + w3cTraceId + "-00f067aa0ba902b7-01"})
+	ntools.TestEq(t, "TestNGinTraceId 使用traceparent", w3cTraceId, w.Header().Get(ntools.TraceIdHeader))
+	tp, ok := ntools.ParseTraceParent(w.Header().Get(ntools.TraceParentHeader))
+	ntools.TestEq(t, "TestNGinTraceId 响应traceparent", true, ok && tp.TraceId == w3cTraceId)
+	traceId, traceParent, _ := strings.Cut(w.Body.String(), "|")
+	ntools.TestEq(t, "TestNGinTraceId 下游X-Trace-Id", w3cTraceId, traceId)
+	tp, ok = ntools.ParseTraceParent(traceParent)
+	ntools.TestEq(t, "TestNGinTraceId 下游traceparent", true, ok && tp.TraceId == w3cTraceId && tp.ParentId != "00f067aa0ba902b7")
+
+	w = doGet(map[string]string{ntools.TraceIdHeader: "APP20250101T0000000001"})
+	ntools.TestEq(t, "TestNGinTraceId 使用X-Trace-Id", "APP20250101T0000000001", w.Header().Get(ntools.TraceIdHeader))
+	ntools.TestStrContains(t, "TestNGinTraceId 下游X-Trace-Id", "APP20250101T0000000001|00-", w.Body.String())
+
+	w = doGet(map[string]string{ntools.TraceIdHeader: "bad\nid", ntools.TraceParentHeader: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"})
+	ntools.TestEq(t, "TestNGinTraceId 非法值重新生成", 32, len(w.Header().Get(ntools.TraceIdHeader)))
+}
@@ -0,0 +1,65 @@
+package ngintest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/njson"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+func TestNGinUpload(t *testing.T) {
+	conf := &nyaml.YamlConfUpload{
+		MaxSizeMb:      1,
+		AllowExts:      []string{".png", ".txt"},
+		AllowMimeTypes: []string{"image/*", "text/plain"},
+		LocalDir:       t.TempDir(),
+		UrlPrefix:      "/files/",
+	}
+	uploader := ngin.NewNUploader(conf, ngin.NewLocalUploadStore(conf))
+	nGin := ngin.NewNGin()
+	nGin.POST("/upload", uploader.HandlerFunc("file"))
+
+	doUpload := func(fileName string, content []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		fw, _ := mw.CreateFormFile("file", fileName)
+		fw.Write(content)
+		mw.Close()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	content := []byte("hello upload")
+	w := doUpload("a.txt", content)
+	resp, err := njson.Str2Obj[struct {
+		Code int                `json:"code"`
+		Data ngin.NUploadResult `json:"data"`
+	}](w.Body.String())
+	ntools.TestErrPainic(t, "TestNGinUpload 解析响应", err)
+	sum := md5.Sum(content)
+	ntools.TestEq(t, "TestNGinUpload code", 0, resp.Code)
+	ntools.TestEq(t, "TestNGinUpload md5", hex.EncodeToString(sum[:]), resp.Data.Md5)
+	ntools.TestEq(t, "TestNGinUpload sm3长度", 64, len(resp.Data.Sm3))
+	ntools.TestEq(t, "TestNGinUpload mime", "text/plain", resp.Data.MimeType)
+	ntools.TestEq(t, "TestNGinUpload url", "/files/"+resp.Data.ObjKey, resp.Data.Url)
+	saved, err := os.ReadFile(filepath.Join(conf.LocalDir, filepath.FromSlash(resp.Data.ObjKey)))
+	ntools.TestErrPainic(t, "TestNGinUpload 读取保存的文件", err)
+	ntools.TestEq(t, "TestNGinUpload 文件内容", string(content), string(saved))
+
+	ntools.TestStrContains(t, "TestNGinUpload 扩展名", "不支持的文件扩展名", doUpload("a.exe", content).Body.String())
+	ntools.TestStrContains(t, "TestNGinUpload 内容识别", "不支持的文件类型", doUpload("a.png", []byte("<html><body>x</body></html>")).Body.String())
+	ntools.TestStrContains(t, "TestNGinUpload 大小", "文件大小不能超过1MB", doUpload("b.txt", bytes.Repeat([]byte("a"), 2<<20)).Body.String())
+}
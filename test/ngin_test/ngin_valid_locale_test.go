@@ -0,0 +1,58 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+type localeReq struct {
+	UserName string `json:"userName" zhdesc:"用户名" binding:"required"`
+	Age      int    `json:"age" zhdesc:"年龄" binding:"gte=18"`
+	Mobile   string `json:"mobile" zhdesc:"手机号" binding:"omitempty,mobile"`
+	Password string `json:"password" zhdesc:"密码"`
+	Confirm  string `json:"confirm" zhdesc:"确认密码" binding:"eqfield=Password"`
+}
+
+func TestValiderLocale(t *testing.T) {
+	nGin := ngin.NewNGin()
+	nGin.POST("/locale", ngin.Handle(nGin.NValider, func(ctx *gin.Context, req *localeReq) (*ngin.EmptyObj, error) {
+		return nil, nil
+	}))
+	doPost := func(headers map[string]string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/locale", strings.NewReader(`{"age":12,"mobile":"123","password":"a","confirm":"b"}`))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	zhBody := doPost(nil)
+	ntools.TestStrContains(t, "TestValiderLocale 默认中文", "用户名[userName]为必填字段", zhBody)
+	ntools.TestStrContains(t, "TestValiderLocale 中文跨字段", "确认密码[confirm]必须等于密码[password]", zhBody)
+
+	enBody := doPost(map[string]string{"Accept-Language": "en-US,en;q=0.9,zh-CN;q=0.8"})
+	ntools.TestStrContains(t, "TestValiderLocale 英文必填", "userName is a required field", enBody)
+	ntools.TestStrContains(t, "TestValiderLocale 英文规则", "mobile must be a valid mobile number", enBody)
+	ntools.TestStrContains(t, "TestValiderLocale 英文跨字段", "confirm must be equal to password", enBody)
+	ntools.TestStrContains(t, "TestValiderLocale 英文默认翻译", "age must be 18 or greater", enBody)
+	ntools.TestEq(t, "TestValiderLocale 英文无中文", false, strings.Contains(enBody, "用户名"))
+
+	headerBody := doPost(map[string]string{"Accept-Language": "en", ngin.LocaleHeader: "zh-CN"})
+	ntools.TestStrContains(t, "TestValiderLocale 请求头优先", "用户名[userName]为必填字段", headerBody)
+	ntools.TestStrContains(t, "TestValiderLocale 中文数值格式", "年龄[age]必须满足条件,大于等于[18]", doPost(map[string]string{"Accept-Language": "fr"}))
+
+	ntools.TestEq(t, "TestValiderLocale 匹配", "en", nGin.NValider.MatchLocale("fr-FR", "en_GB"))
+	ntools.TestEq(t, "TestValiderLocale 未匹配", "zh", nGin.NValider.MatchLocale("ja"))
+
+	err := nGin.NValider.TransErr("en", nil, nGin.NValider.ValidStrct(&localeReq{Age: 20, UserName: "a", Password: "a"}))
+	ntools.TestStrContains(t, "TestValiderLocale 无类型信息", "confirm must be equal to Password", err.Error())
+}
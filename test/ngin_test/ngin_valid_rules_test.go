@@ -0,0 +1,88 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+)
+
+func TestValiderRules(t *testing.T) {
+	ntools.TestEq(t, "TestValiderRules 身份证", true, ngin.IsIdCard("11010519491231002X"))
+	ntools.TestEq(t, "TestValiderRules 身份证小写x", true, ngin.IsIdCard("11010519491231002x"))
+	ntools.TestEq(t, "TestValiderRules 身份证校验码", false, ngin.IsIdCard("110105194912310021"))
+	ntools.TestEq(t, "TestValiderRules 身份证日期", false, ngin.IsIdCard("110105194902310026"))
+	ntools.TestEq(t, "TestValiderRules 信用代码", true, ngin.IsUscc("91350100M000100Y43"))
+	ntools.TestEq(t, "TestValiderRules 信用代码校验码", false, ngin.IsUscc("91350100M000100Y44"))
+	ntools.TestEq(t, "TestValiderRules 手机号", true, ngin.IsMobile("+8613800138000"))
+	ntools.TestEq(t, "TestValiderRules 手机号错误", false, ngin.IsMobile("12800138000"))
+	ntools.TestEq(t, "TestValiderRules 车牌", true, ngin.IsPlateNo("川A12345"))
+	ntools.TestEq(t, "TestValiderRules 新能源车牌", true, ngin.IsPlateNo("粤BD12345"))
+	ntools.TestEq(t, "TestValiderRules 车牌含I", false, ngin.IsPlateNo("川AI2345"))
+
+	valider := ngin.NewNValider("json", "zhdesc")
+	type UserVo struct {
+		IdCard   string `json:"idCard" zhdesc:"身份证号" binding:"required,idcard"`
+		Mobile   string `json:"mobile" zhdesc:"手机号" binding:"omitempty,mobile"`
+		Uscc     string `json:"uscc" zhdesc:"信用代码" binding:"omitempty,uscc"`
+		Postcode string `json:"postcode" zhdesc:"邮编" binding:"omitempty,postcode"`
+		Plate    string `json:"plate" zhdesc:"车牌" binding:"omitempty,plate"`
+	}
+	err := valider.TransErr2ZhErr(valider.ValidStrct(&UserVo{IdCard: "110105194912310021", Mobile: "138", Uscc: "1", Postcode: "61000", Plate: "A12345"}))
+	ntools.TestStrContains(t, "TestValiderRules idcard", "身份证号[idCard]必须是有效的身份证号", err.Error())
+	ntools.TestStrContains(t, "TestValiderRules mobile", "手机号[mobile]必须是有效的手机号", err.Error())
+	ntools.TestStrContains(t, "TestValiderRules uscc", "信用代码[uscc]必须是有效的统一社会信用代码", err.Error())
+	ntools.TestStrContains(t, "TestValiderRules postcode", "邮编[postcode]必须是有效的邮政编码", err.Error())
+	ntools.TestStrContains(t, "TestValiderRules plate", "车牌[plate]必须是有效的车牌号", err.Error())
+
+	err = valider.RegisterStrRule("bankcard", func(s string) bool { return len(s) >= 16 }, "{0}必须是有效的银行卡号")
+	ntools.TestErrPainic(t, "TestValiderRules 自定义规则", err)
+	type CardVo struct {
+		Card string `json:"card" zhdesc:"银行卡" binding:"bankcard"`
+	}
+	err = valider.TransErr2ZhErr(valider.ValidStrct(&CardVo{Card: "62"}))
+	ntools.TestStrContains(t, "TestValiderRules 自定义规则翻译", "银行卡[card]必须是有效的银行卡号", err.Error())
+	_, ok := err.(*ngin.NiexqValidErr)
+	ntools.TestEq(t, "TestValiderRules 错误类型", true, ok)
+}
+
+type crossFieldAddrVo struct {
+	Type    int    `json:"type" zhdesc:"类型"`
+	Company string `json:"company" zhdesc:"单位名称" binding:"required_if=Type 2"`
+}
+
+type crossFieldReq struct {
+	Password  string              `json:"password" zhdesc:"密码" binding:"required"`
+	Confirm   string              `json:"confirm" zhdesc:"确认密码" binding:"eqfield=Password"`
+	Phone     string              `json:"phone,omitempty" zhdesc:"手机号"`
+	Email     string              `json:"email" zhdesc:"邮箱" binding:"required_without=Phone"`
+	StartDate int                 `json:"startDate" zhdesc:"开始日期"`
+	EndDate   int                 `json:"endDate" zhdesc:"结束日期" binding:"gtefield=StartDate"`
+	AddrList  []*crossFieldAddrVo `json:"addrList" zhdesc:"地址" binding:"dive"`
+}
+
+func TestValiderCrossField(t *testing.T) {
+	nGin := ngin.NewNGin()
+	nGin.POST("/cross", func(ctx *gin.Context) {
+		if _, err := ngin.ShouldBindJSON[crossFieldReq](ctx, nGin.NValider); err != nil {
+			ngin.AbortWithErr(ctx, err)
+		}
+	})
+	w := httptest.NewRecorder()
+	body := `{"password":"a","confirm":"b","startDate":20250102,"endDate":20250101,"addrList":[{"type":2}]}`
+	req := httptest.NewRequest(http.MethodPost, "/cross", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	nGin.GinEngine.ServeHTTP(w, req)
+
+	ntools.TestStrContains(t, "TestValiderCrossField eqfield", "确认密码[confirm]必须等于密码[password]", w.Body.String())
+	ntools.TestStrContains(t, "TestValiderCrossField required_without", "邮箱[email]在手机号[phone]任一为空时为必填字段", w.Body.String())
+	ntools.TestStrContains(t, "TestValiderCrossField gtefield", "结束日期[endDate]必须大于或等于开始日期[startDate]", w.Body.String())
+	ntools.TestStrContains(t, "TestValiderCrossField required_if", "单位名称[company]在类型[type]为2时为必填字段", w.Body.String())
+
+	err := nGin.NValider.TransErr2ZhErr(nGin.NValider.ValidStrct(&crossFieldReq{Password: "a", Email: "e"}))
+	ntools.TestStrContains(t, "TestValiderCrossField 无类型信息", "确认密码[confirm]必须等于Password", err.Error())
+}
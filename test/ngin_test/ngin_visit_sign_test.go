@@ -0,0 +1,62 @@
+package ngintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/niexqc/nlibs/ncache"
+	"github.com/niexqc/nlibs/ndnen"
+	"github.com/niexqc/nlibs/ngin"
+	"github.com/niexqc/nlibs/ntools"
+	"github.com/niexqc/nlibs/nyaml"
+)
+
+func TestNGinVisitSign(t *testing.T) {
+	pri, pub := ndnen.Sm2GenKeyPair()
+	_, pubHex := ndnen.Sm2Key2Hex(pri, pub)
+	conf := &nyaml.YamlConfVisitSign{
+		TimeWindow:  60,
+		NoncePrefix: "sign:",
+		VisitSrcKeys: []nyaml.YamlConfVisitSrcKey{
+			{VisitSrc: "web", SignType: ngin.VisitSignTypeHmacSm3, HmacKey: "web-secret"},
+			{VisitSrc: "app", SignType: ngin.VisitSignTypeSm2, Sm2HexPubKey: pubHex},
+		},
+	}
+	nGin := ngin.NewNGin()
+	nGin.Use(ngin.VisitSignHandlerFunc(conf, ncache.NewMemCacheService(time.Minute)))
+	nGin.POST("/api/save", func(ctx *gin.Context) {
+		body, _ := ctx.GetRawData()
+		ctx.JSON(http.StatusOK, ngin.NewOkBaseResp(string(body)))
+	})
+
+	doPost := func(visitSrc, clientTime, onceStr, body string, sign func(srcStr string) string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/save?id=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Visit-Src", visitSrc)
+		req.Header.Set("Client-Time", clientTime)
+		req.Header.Set("Onece-Str", onceStr)
+		req.Header.Set("Visit-Sign", sign(ngin.VisitSignSrcStr(http.MethodPost, "/api/save?id=1", []byte(body), clientTime, onceStr)))
+		nGin.GinEngine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	hmacSign := func(srcStr string) string { return ndnen.HmacSm3Hex("web-secret", srcStr) }
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	ntools.TestStrContains(t, "TestNGinVisitSign hmac成功", `"data":"{\"a\":1}"`, doPost("web", now, "n1", `{"a":1}`, hmacSign))
+	ntools.TestStrContains(t, "TestNGinVisitSign 重放", "重复的请求", doPost("web", now, "n1", `{"a":1}`, hmacSign))
+	ntools.TestStrContains(t, "TestNGinVisitSign 签名错误", "签名错误", doPost("web", now, "n2", `{"a":1}`, func(srcStr string) string {
+		return ndnen.HmacSm3Hex("other", srcStr)
+	}))
+	expired := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+	ntools.TestStrContains(t, "TestNGinVisitSign 过期", `"code":4030`, doPost("web", expired, "n3", `{"a":1}`, hmacSign))
+	ntools.TestStrContains(t, "TestNGinVisitSign 未知来源", "未知的请求源", doPost("h5", now, "n4", `{"a":1}`, hmacSign))
+	ntools.TestStrContains(t, "TestNGinVisitSign sm2成功", `"code":0`, doPost("app", now, "n5", `{"b":2}`, func(srcStr string) string {
+		return ndnen.Sm2SignByPriKey(pri, srcStr)
+	}))
+}
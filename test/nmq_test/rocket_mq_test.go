@@ -43,3 +43,7 @@ func TestNmqProducerAndConsumer(t *testing.T) {
 	recvMsgId := <-recv
 	ntools.TestStrContains(t, "NMqConsumer 接收刚发送的消息", msgId, recvMsgId)
 }
+
+func TestNmqProducerReady(t *testing.T) {
+	ntools.TestErrNotNil(t, "NMqProduer 未启动", (&nmq.NMqProduer{}).Ready())
+}